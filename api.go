@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// 默认和最大分页大小
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// 文件元数据
+type apiFileInfo struct {
+	ID      string     `json:"id"`
+	Name    string     `json:"name"`
+	Size    int64      `json:"size"`
+	Summary harSummary `json:"summary"`
+}
+
+// 条目列表中的一项
+type apiEntry struct {
+	Index           int     `json:"index"`
+	StartedDateTime string  `json:"startedDateTime"`
+	Method          string  `json:"method"`
+	URL             string  `json:"url"`
+	Domain          string  `json:"domain"`
+	Status          int     `json:"status"`
+	StatusText      string  `json:"statusText"`
+	MimeType        string  `json:"mimeType"`
+	Size            int64   `json:"size"`
+	Time            float64 `json:"time"`
+	Pageref         string  `json:"pageref,omitempty"`
+}
+
+// 分页后的条目列表
+type apiEntryList struct {
+	Total   int        `json:"total"`
+	Offset  int        `json:"offset"`
+	Limit   int        `json:"limit"`
+	Entries []apiEntry `json:"entries"`
+}
+
+// 设置API路由
+func setupAPIRoutes() {
+	http.HandleFunc("/api/files", apiFilesHandler)
+	http.HandleFunc("/api/entries", apiEntriesHandler)
+	http.HandleFunc("/api/entry", apiEntryHandler)
+	http.HandleFunc("/api/domains", apiDomainsHandler)
+	http.HandleFunc("/api/endpoints", apiEndpointsHandler)
+}
+
+// 输出JSON响应
+func writeAPIJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+// 输出JSON格式的错误
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// 根据请求参数获取文件，失败时输出JSON错误
+func apiFileFromRequest(w http.ResponseWriter, r *http.Request) (*harFile, bool) {
+	sid := sessionFromRequest(r)
+	if sid == "" {
+		writeAPIError(w, http.StatusNotFound, "会话不存在")
+		return nil, false
+	}
+	file := store.acquireFile(r, sid, r.URL.Query().Get("fid"))
+	if file == nil {
+		writeAPIError(w, http.StatusNotFound, "未加载HAR文件")
+		return nil, false
+	}
+	return file, true
+}
+
+// 读取整数参数，缺省或无效时使用默认值
+func intParam(r *http.Request, name string, defaultValue int) int {
+	value, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// 生成文件元数据
+func fileInfo(file *harFile) apiFileInfo {
+	return apiFileInfo{
+		ID:      file.ID,
+		Name:    file.Name,
+		Size:    file.Size,
+		Summary: summarizeHAR(file),
+	}
+}
+
+// 生成条目列表项
+func newAPIEntry(index int, entry *Entry) apiEntry {
+	return apiEntry{
+		Index:           index,
+		StartedDateTime: entry.StartedDateTime,
+		Method:          entry.Request.Method,
+		URL:             entry.Request.URL,
+		Domain:          extractDomain(entry.Request.URL),
+		Status:          entry.Response.Status,
+		StatusText:      entry.Response.StatusText,
+		MimeType:        entry.Response.Content.MimeType,
+		Size:            entry.Response.Content.Size,
+		Time:            entry.Time,
+		Pageref:         entry.Pageref,
+	}
+}
+
+// 会话中的文件列表，指定 fid 时只返回该文件
+func apiFilesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("fid") != "" {
+		file, ok := apiFileFromRequest(w, r)
+		if !ok {
+			return
+		}
+		writeAPIJSON(w, fileInfo(file))
+		return
+	}
+
+	sid := sessionFromRequest(r)
+	if sid == "" {
+		writeAPIError(w, http.StatusNotFound, "会话不存在")
+		return
+	}
+	files := []apiFileInfo{}
+	for _, file := range store.files(sid) {
+		files = append(files, fileInfo(file))
+	}
+	writeAPIJSON(w, files)
+}
+
+// 分页条目列表，filter 参数使用与页面相同的过滤语法
+// method、status、domain、q 参数直接生成对应的过滤条件，值不按过滤语法解析
+func apiEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	file, ok := apiFileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter, err := parseFilter(query.Get("filter"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, param := range []struct{ name, key string }{
+		{"method", "method"}, {"status", "status"}, {"domain", "domain"}, {"q", "url"},
+	} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		term, err := newFilterTerm(param.key, value)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		filter.terms = append(filter.terms, term)
+	}
+
+	offset := intParam(r, "offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+	limit := intParam(r, "limit", defaultPageSize)
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+
+	indexes, err := filter.apply(file)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	result := apiEntryList{Total: len(indexes), Offset: offset, Limit: limit, Entries: []apiEntry{}}
+	for i := offset; i < len(indexes) && i < offset+limit; i++ {
+		result.Entries = append(result.Entries, newAPIEntry(indexes[i], &file.HAR.Log.Entries[indexes[i]]))
+	}
+	writeAPIJSON(w, result)
+}
+
+// 单个完整条目，包括请求头、响应头、请求体和响应体
+func apiEntryHandler(w http.ResponseWriter, r *http.Request) {
+	file, ok := apiFileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	index, err := strconv.Atoi(r.URL.Query().Get("index"))
+	if err != nil || index < 0 || index >= len(file.HAR.Log.Entries) {
+		writeAPIError(w, http.StatusBadRequest, "无效的请求序号")
+		return
+	}
+
+	entry, err := file.Entry(index)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeAPIJSON(w, entry)
+}
+
+// 域名列表
+func apiDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	file, ok := apiFileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	domains := extractUniqueDomains(file.HAR)
+	if domains == nil {
+		domains = []string{}
+	}
+	writeAPIJSON(w, domains)
+}
+
+// 按接口模板聚合的统计，支持 filter 和 sort 参数
+func apiEndpointsHandler(w http.ResponseWriter, r *http.Request) {
+	file, ok := apiFileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	filter, err := parseFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	indexes, err := filter.apply(file)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	endpoints := buildEndpointStats(file.HAR, indexes, r.URL.Query().Get("sort"))
+	if endpoints == nil {
+		endpoints = []*endpointStats{}
+	}
+	writeAPIJSON(w, endpoints)
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/net/html"
+)
+
+// 十六进制视图最多显示的字节数
+const maxHexBytes = 64 << 10
+
+// 解压后的内容最多保留的字节数，防止很小的压缩数据解压出超大内容
+const maxDecompressedSize = 32 << 20
+
+// 解压后的内容超过限制，返回的数据已截断
+var errDecompressedTooLarge = fmt.Errorf("解压后的内容超过 %d MB，只显示前 %d MB", maxDecompressedSize>>20, maxDecompressedSize>>20)
+
+// 响应体视图数据
+type bodyView struct {
+	MimeType    string
+	SizeText    string
+	Encoding    string
+	Compression string
+	Kind        string
+	Pretty      string
+	Raw         string
+	Hex         string
+	HexLimited  bool
+	ImageURI    template.URL
+	DefaultTab  string
+	Error       string
+	Empty       bool
+}
+
+// 不需要闭合的HTML元素
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// 获取指定名称的头部值（不区分大小写）
+func headerValue(headers []Header, name string) string {
+	for _, header := range headers {
+		if strings.EqualFold(header.Name, name) {
+			return header.Value
+		}
+	}
+	return ""
+}
+
+// 去掉MIME类型中的参数部分并转为小写
+func baseMimeType(mimeType string) string {
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// 解码base64内容，兼容URL安全编码和缺少填充的情况
+func decodeBase64(text string) ([]byte, error) {
+	text = strings.TrimSpace(text)
+	encodings := []*base64.Encoding{
+		base64.StdEncoding,
+		base64.RawStdEncoding,
+		base64.URLEncoding,
+		base64.RawURLEncoding,
+	}
+	var firstErr error
+	for _, enc := range encodings {
+		data, err := enc.DecodeString(text)
+		if err == nil {
+			return data, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// 读取解压后的内容，超过 maxDecompressedSize 时截断并返回 errDecompressedTooLarge
+func readDecompressed(reader io.Reader) ([]byte, error) {
+	out, err := io.ReadAll(io.LimitReader(reader, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxDecompressedSize {
+		return out[:maxDecompressedSize], errDecompressedTooLarge
+	}
+	return out, nil
+}
+
+// 解压缩内容，返回解压后的数据和使用的压缩算法，支持 gzip、deflate、br 和 zstd
+// 多数抓包工具保存的是已解压的内容，因此只有数据确实是压缩格式时才解压
+func decompressBody(data []byte, contentEncoding string) ([]byte, string, error) {
+	contentEncoding = strings.ToLower(strings.TrimSpace(contentEncoding))
+
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return data, "", err
+		}
+		defer reader.Close()
+		out, err := readDecompressed(reader)
+		if out == nil {
+			return data, "", err
+		}
+		return out, "gzip", err
+	}
+	if bytes.HasPrefix(data, []byte{0x28, 0xb5, 0x2f, 0xfd}) {
+		decoder, err := zstd.NewReader(bytes.NewReader(data), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return data, "", err
+		}
+		defer decoder.Close()
+		out, err := readDecompressed(decoder)
+		if out == nil {
+			return data, "", fmt.Errorf("zstd 解压失败: %v", err)
+		}
+		return out, "zstd", err
+	}
+
+	switch contentEncoding {
+	case "", "identity", "gzip", "x-gzip":
+	case "deflate":
+		if reader, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+			defer reader.Close()
+			if out, err := readDecompressed(reader); out != nil {
+				return out, "deflate", err
+			}
+		}
+		reader := flate.NewReader(bytes.NewReader(data))
+		defer reader.Close()
+		if out, err := readDecompressed(reader); out != nil {
+			return out, "deflate", err
+		}
+	case "br":
+		// br 没有文件头，解压失败且内容是文本时认为已解压
+		out, err := readDecompressed(brotli.NewReader(bytes.NewReader(data)))
+		if out != nil {
+			return out, "br", err
+		}
+		if !utf8.Valid(data) {
+			return data, "", fmt.Errorf("br 解压失败: %v", err)
+		}
+	default:
+		// 其他压缩算法不解压，内容不是文本时提示
+		if !utf8.Valid(data) {
+			return data, "", fmt.Errorf("不支持解压 %s 编码的内容", contentEncoding)
+		}
+	}
+	return data, "", nil
+}
+
+// 获取解码后的内容数据
+func decodeContent(content Content, headers []Header) ([]byte, string, error) {
+	data := []byte(content.Text)
+	if strings.EqualFold(content.Encoding, "base64") {
+		decoded, err := decodeBase64(content.Text)
+		if err != nil {
+			return data, "", fmt.Errorf("base64解码失败: %v", err)
+		}
+		data = decoded
+	}
+	return decompressBody(data, headerValue(headers, "Content-Encoding"))
+}
+
+// 判断数据是否为可显示的文本
+func isPrintableText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, b := range data {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' {
+			return false
+		}
+	}
+	return true
+}
+
+// 根据MIME类型判断内容种类
+func contentKind(mimeType string, data []byte) string {
+	mimeType = baseMimeType(mimeType)
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return "image"
+	case strings.Contains(mimeType, "json"):
+		return "json"
+	case strings.Contains(mimeType, "html"):
+		return "html"
+	case strings.Contains(mimeType, "xml"):
+		return "xml"
+	case isPrintableText(data):
+		return "text"
+	default:
+		return "binary"
+	}
+}
+
+// 格式化XML
+func indentXML(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	var buf bytes.Buffer
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if charData, ok := token.(xml.CharData); ok {
+			trimmed := bytes.TrimSpace(charData)
+			if len(trimmed) == 0 {
+				continue
+			}
+			token = xml.CharData(trimmed)
+		}
+		if err := encoder.EncodeToken(xml.CopyToken(token)); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 格式化HTML，每个标签单独一行并按层级缩进
+func indentHTML(data []byte) []byte {
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	var buf bytes.Buffer
+	depth := 0
+
+	writeLine := func(text string) {
+		buf.WriteString(strings.Repeat("  ", depth))
+		buf.WriteString(text)
+		buf.WriteByte('\n')
+	}
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return buf.Bytes()
+		}
+
+		raw := string(tokenizer.Raw())
+		switch tokenType {
+		case html.TextToken:
+			if text := strings.TrimSpace(raw); text != "" {
+				writeLine(text)
+			}
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			writeLine(raw)
+			if !voidElements[string(name)] {
+				depth++
+			}
+		case html.EndTagToken:
+			if depth > 0 {
+				depth--
+			}
+			writeLine(raw)
+		default:
+			writeLine(strings.TrimSpace(raw))
+		}
+	}
+}
+
+// 生成格式化后的内容
+func prettyBody(kind string, data []byte) (string, error) {
+	switch kind {
+	case "json":
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, "", "  "); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	case "xml":
+		out, err := indentXML(data)
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	case "html":
+		return string(indentHTML(data)), nil
+	}
+	return "", nil
+}
+
+// 生成十六进制视图
+func hexView(data []byte) (string, bool) {
+	if len(data) > maxHexBytes {
+		return hex.Dump(data[:maxHexBytes]), true
+	}
+	return hex.Dump(data), false
+}
+
+// 构建响应体视图
+func buildBodyView(content Content, headers []Header) bodyView {
+	view := bodyView{
+		MimeType: content.MimeType,
+		SizeText: formatFileSize(int(content.Size)),
+		Encoding: content.Encoding,
+	}
+
+	if content.Text == "" {
+		view.Empty = true
+		return view
+	}
+
+	data, compression, err := decodeContent(content, headers)
+	if err != nil {
+		view.Error = err.Error()
+	}
+	view.Compression = compression
+	view.Kind = contentKind(content.MimeType, data)
+	view.Hex, view.HexLimited = hexView(data)
+
+	switch view.Kind {
+	case "image":
+		view.ImageURI = template.URL("data:" + baseMimeType(content.MimeType) + ";base64," + base64.StdEncoding.EncodeToString(data))
+		view.DefaultTab = "image"
+	case "binary":
+		view.DefaultTab = "hex"
+	default:
+		view.Raw = string(data)
+		view.DefaultTab = "raw"
+		if pretty, err := prettyBody(view.Kind, data); err == nil && pretty != "" {
+			view.Pretty = pretty
+			view.DefaultTab = "pretty"
+		}
+	}
+	return view
+}
+
+var responseBodyTemplate = `<div class="body-view">
+    <p class="body-meta">
+        类型: {{.MimeType}} | 大小: {{.SizeText}}
+        {{if .Encoding}} | 编码: {{.Encoding}}{{end}}
+        {{if .Compression}} | 已解压: {{.Compression}}{{end}}
+    </p>
+    {{if .Error}}<p class="error-message">{{.Error}}</p>{{end}}
+    {{if .Empty}}
+    <p>无响应体内容</p>
+    {{else}}
+    <div class="body-tabs">
+        {{if .ImageURI}}<button type="button" class="btn body-tab{{if eq .DefaultTab "image"}} active{{end}}" onclick="switchBodyTab(this, 'image')">图片</button>{{end}}
+        {{if .Pretty}}<button type="button" class="btn body-tab{{if eq .DefaultTab "pretty"}} active{{end}}" onclick="switchBodyTab(this, 'pretty')">格式化</button>{{end}}
+        {{if .Raw}}<button type="button" class="btn body-tab{{if eq .DefaultTab "raw"}} active{{end}}" onclick="switchBodyTab(this, 'raw')">原始</button>{{end}}
+        <button type="button" class="btn body-tab{{if eq .DefaultTab "hex"}} active{{end}}" onclick="switchBodyTab(this, 'hex')">十六进制</button>
+    </div>
+    {{if .ImageURI}}<div class="body-pane" data-tab="image"{{if ne .DefaultTab "image"}} style="display: none;"{{end}}><img src="{{.ImageURI}}" alt="响应图片"></div>{{end}}
+    {{if .Pretty}}<pre class="body-pane" data-tab="pretty"{{if ne .DefaultTab "pretty"}} style="display: none;"{{end}}>{{.Pretty}}</pre>{{end}}
+    {{if .Raw}}<pre class="body-pane" data-tab="raw"{{if ne .DefaultTab "raw"}} style="display: none;"{{end}}>{{.Raw}}</pre>{{end}}
+    <pre class="body-pane hex-pane" data-tab="hex"{{if ne .DefaultTab "hex"}} style="display: none;"{{end}}>{{.Hex}}{{if .HexLimited}}
+... 仅显示前 64 KB{{end}}</pre>
+    {{end}}
+</div>`
+
+// 根据请求参数获取会话文件和条目序号
+func entryIndexFromRequest(w http.ResponseWriter, r *http.Request) (string, *harFile, int, bool) {
+	sid, file, ok := fileFromRequest(w, r)
+	if !ok {
+		return "", nil, 0, false
+	}
+
+	index, err := strconv.Atoi(r.URL.Query().Get("index"))
+	if err != nil || index < 0 || index >= len(file.HAR.Log.Entries) {
+		http.Error(w, "无效的请求序号", http.StatusBadRequest)
+		return "", nil, 0, false
+	}
+	return sid, file, index, true
+}
+
+// 根据请求参数获取会话文件中的完整条目
+func entryFromRequest(w http.ResponseWriter, r *http.Request) (*Entry, bool) {
+	_, file, index, ok := entryIndexFromRequest(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	entry, err := file.Entry(index)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return &entry, true
+}
+
+// 响应体处理函数
+func responseBodyHandler(w http.ResponseWriter, r *http.Request) {
+	entry, ok := entryFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tmpl, err := template.New("response-body").Parse(responseBodyTemplate)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("解析模板失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	view := buildBodyView(entry.Response.Content, entry.Response.Headers)
+	tmpl.Execute(w, view)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// 用指定算法压缩数据
+func compressTestData(t *testing.T, encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case "gzip":
+		writer = gzip.NewWriter(&buf)
+	case "zlib":
+		writer = zlib.NewWriter(&buf)
+	case "flate":
+		writer, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		writer = brotli.NewWriter(&buf)
+	case "zstd":
+		var err error
+		if writer, err = zstd.NewWriter(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writer.Write(data)
+	writer.Close()
+	return buf.Bytes()
+}
+
+func TestDecompressBody(t *testing.T) {
+	text := []byte(strings.Repeat(`{"message":"hello"}`, 100))
+	tests := []struct {
+		name        string
+		data        []byte
+		encoding    string
+		compression string
+	}{
+		{"gzip", compressTestData(t, "gzip", text), "gzip", "gzip"},
+		{"gzip 无请求头", compressTestData(t, "gzip", text), "", "gzip"},
+		{"zlib", compressTestData(t, "zlib", text), "deflate", "deflate"},
+		{"原始 deflate", compressTestData(t, "flate", text), "deflate", "deflate"},
+		{"br", compressTestData(t, "br", text), "br", "br"},
+		{"zstd", compressTestData(t, "zstd", text), "zstd", "zstd"},
+		{"zstd 无请求头", compressTestData(t, "zstd", text), "", "zstd"},
+		{"br 已解压", text, "br", ""},
+		{"未压缩", text, "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, compression, err := decompressBody(test.data, test.encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if compression != test.compression {
+				t.Errorf("压缩算法为 %q，应为 %q", compression, test.compression)
+			}
+			if !bytes.Equal(out, text) {
+				t.Errorf("解压结果不一致: %.40q", out)
+			}
+		})
+	}
+}
+
+// 解压后超过上限时截断并提示，不会读取全部内容
+func TestDecompressBodyLimit(t *testing.T) {
+	large := make([]byte, maxDecompressedSize+1024)
+	for _, encoding := range []string{"gzip", "br", "zstd"} {
+		out, compression, err := decompressBody(compressTestData(t, encoding, large), encoding)
+		if !errors.Is(err, errDecompressedTooLarge) {
+			t.Errorf("%s: 超过上限时返回 %v", encoding, err)
+		}
+		if len(out) != maxDecompressedSize || compression != encoding {
+			t.Errorf("%s: 截断后为 %d 字节，压缩算法 %q", encoding, len(out), compression)
+		}
+	}
+}
+
+// 无法解压的二进制内容返回错误并保留原始数据
+func TestDecompressBodyInvalid(t *testing.T) {
+	data := []byte{0xff, 0xfe, 0x00, 0x01}
+	for _, encoding := range []string{"br", "compress"} {
+		out, _, err := decompressBody(data, encoding)
+		if err == nil || !bytes.Equal(out, data) {
+			t.Errorf("%s: 返回 %v %v", encoding, out, err)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Charles 导出的 JSON 会话（.chlsj）
+type charlesSession struct {
+	Status          string `json:"status"`
+	Method          string `json:"method"`
+	ProtocolVersion string `json:"protocolVersion"`
+	Scheme          string `json:"scheme"`
+	Host            string `json:"host"`
+	ActualPort      int    `json:"actualPort"`
+	Path            string `json:"path"`
+	Query           string `json:"query"`
+	Tunnel          bool   `json:"tunnel"`
+	RemoteAddress   string `json:"remoteAddress"`
+	ErrorMessage    string `json:"errorMessage"`
+	Times           struct {
+		Start         string `json:"start"`
+		RequestBegin  string `json:"requestBegin"`
+		ResponseBegin string `json:"responseBegin"`
+		End           string `json:"end"`
+	} `json:"times"`
+	Durations struct {
+		Total    *float64 `json:"total"`
+		DNS      *float64 `json:"dns"`
+		Connect  *float64 `json:"connect"`
+		SSL      *float64 `json:"ssl"`
+		Request  *float64 `json:"request"`
+		Latency  *float64 `json:"latency"`
+		Response *float64 `json:"response"`
+	} `json:"durations"`
+	Request  charlesMessage `json:"request"`
+	Response charlesMessage `json:"response"`
+}
+
+// Charles 会话中的请求或响应
+type charlesMessage struct {
+	Status   int    `json:"status"`
+	MimeType string `json:"mimeType"`
+	Charset  string `json:"charset"`
+	Sizes    struct {
+		Headers int64 `json:"headers"`
+		Body    int64 `json:"body"`
+	} `json:"sizes"`
+	Header struct {
+		FirstLine string   `json:"firstLine"`
+		Headers   []Header `json:"headers"`
+	} `json:"header"`
+	Body *struct {
+		Text    string `json:"text"`
+		Encoded string `json:"encoded"` // 二进制内容为 base64
+	} `json:"body"`
+}
+
+// 文件开头为 JSON 数组且包含 Charles 特有的字段
+func sniffCharles(head []byte) bool {
+	trimmed := bytes.TrimLeft(head, " \t\r\n\ufeff")
+	return bytes.HasPrefix(trimmed, []byte("[")) &&
+		(bytes.Contains(head, []byte(`"actualPort"`)) || bytes.Contains(head, []byte(`"protocolVersion"`)))
+}
+
+// 可选的毫秒数，未记录时为 -1
+func charlesDuration(value *float64) float64 {
+	if value == nil {
+		return -1
+	}
+	return *value
+}
+
+// 解析 Charles 的时间，格式如 2024-01-01T10:00:00.123+08:00
+func parseCharlesTime(value string) time.Time {
+	t, _ := parseHARTime(value)
+	return t
+}
+
+// 获取消息体内容
+func (m *charlesMessage) body() []byte {
+	if m.Body == nil {
+		return nil
+	}
+	if m.Body.Encoded != "" {
+		data, err := base64.StdEncoding.DecodeString(m.Body.Encoded)
+		if err == nil {
+			return data
+		}
+	}
+	return []byte(m.Body.Text)
+}
+
+// 获取完整的 MIME 类型
+func (m *charlesMessage) mimeType() string {
+	if m.MimeType == "" || m.Charset == "" {
+		return m.MimeType
+	}
+	return m.MimeType + "; charset=" + m.Charset
+}
+
+// 转换 Charles 的 JSON 会话文件，逐个解析会话
+func convertCharles(r io.Reader, emit func(*Entry) error) error {
+	decoder := json.NewDecoder(r)
+	if err := expectDelim(decoder, '['); err != nil {
+		return err
+	}
+
+	for decoder.More() {
+		var session charlesSession
+		if err := decoder.Decode(&session); err != nil {
+			return err
+		}
+		// HTTPS 隧道（未解密的 CONNECT）没有HTTP内容
+		if session.Tunnel && strings.EqualFold(session.Method, "CONNECT") {
+			continue
+		}
+
+		host := session.Host
+		if session.ActualPort > 0 && !(session.Scheme == "http" && session.ActualPort == 80) && !(session.Scheme == "https" && session.ActualPort == 443) {
+			host += ":" + strconv.Itoa(session.ActualPort)
+		}
+		rawURL := session.Scheme + "://" + host + session.Path
+		if session.Query != "" {
+			rawURL += "?" + session.Query
+		}
+
+		started := parseCharlesTime(session.Times.Start)
+		entry := newImportedEntry(session.Method, rawURL, session.ProtocolVersion, started)
+		entry.ServerIPAddress = session.RemoteAddress
+		if session.Request.Header.Headers != nil {
+			entry.Request.Headers = session.Request.Header.Headers
+		}
+		entry.Request.HeadersSize = session.Request.Sizes.Headers
+		entry.Request.BodySize = session.Request.Sizes.Body
+		entry.Request.PostData = importedPostData(session.Request.body(), session.Request.mimeType())
+
+		entry.Response.Status = session.Response.Status
+		if _, statusText, ok := strings.Cut(session.Response.Header.FirstLine, " "); ok {
+			_, entry.Response.StatusText, _ = strings.Cut(statusText, " ")
+		}
+		if session.Response.Header.Headers != nil {
+			entry.Response.Headers = session.Response.Header.Headers
+		}
+		entry.Response.HeadersSize = session.Response.Sizes.Headers
+		entry.Response.BodySize = session.Response.Sizes.Body
+		entry.Response.Content = importedContent(session.Response.body(), session.Response.mimeType())
+		entry.Response.RedirectURL = headerValue(entry.Response.Headers, "Location")
+		if session.ErrorMessage != "" {
+			entry.Comment = session.ErrorMessage
+		}
+
+		entry.Time = charlesDuration(session.Durations.Total)
+		if entry.Time < 0 {
+			entry.Time = max(millisBetween(started, parseCharlesTime(session.Times.End)), 0)
+		}
+		entry.Timings.DNS = charlesDuration(session.Durations.DNS)
+		entry.Timings.Connect = charlesDuration(session.Durations.Connect)
+		entry.Timings.SSL = charlesDuration(session.Durations.SSL)
+		// Charles 分别记录连接和 SSL 握手的耗时，HAR 中 connect 包含 ssl 阶段
+		if entry.Timings.SSL > 0 {
+			entry.Timings.Connect = max(entry.Timings.Connect, 0) + entry.Timings.SSL
+		}
+		entry.Timings.Send = max(charlesDuration(session.Durations.Request), 0)
+		entry.Timings.Wait = max(charlesDuration(session.Durations.Latency), 0)
+		entry.Timings.Receive = max(charlesDuration(session.Durations.Response), 0)
+		if err := emit(&entry); err != nil {
+			return err
+		}
+	}
+	return expectDelim(decoder, ']')
+}
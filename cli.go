@@ -0,0 +1,479 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// 命令行用法说明
+const cliUsage = `用法: harviewer <命令> [参数]
+
+命令:
+  summary [--json] <文件.har>...          输出HAR文件概要（请求数量、方法、状态码、域名等）
+  domains [--json] <文件.har>...          输出所有唯一域名
+  serve [--port 8081] [--open] [文件.har]...  不启动GUI，直接运行Web服务，可预先加载文件
+  mock [--port 9000] [--ignore-query] [--match-host] [--match-body] <文件.har>
+                                          启动Mock服务，按方法、路径和查询参数匹配录制的请求并返回录制的响应
+  compare [--json] [--ignore-query-values] [--threshold 20] <基准.har> <对比.har>
+                                          对比两个HAR文件，输出新增、删除、变化的请求和变慢的接口
+  export [--format har|postman|openapi] [--filter 条件] [-o 输出文件] <文件>
+                                          导出为HAR 1.2、Postman集合或推断的OpenAPI 3文档，默认输出到标准输出
+  help                                    显示本说明
+
+不带参数运行时启动GUI界面
+
+除HAR文件外，也可以直接打开 Charles（.chlsj）、Fiddler（.saz）、mitmproxy 流文件、
+Chrome NetLog（chrome://net-export 导出的 .json）和 pcap 抓包文件，加载时自动转换为HAR`
+
+// HAR文件概要
+type harSummary struct {
+	File          string         `json:"file"`
+	Size          int64          `json:"size"`
+	Version       string         `json:"version"`
+	Creator       string         `json:"creator"`
+	Pages         int            `json:"pages"`
+	Entries       int            `json:"entries"`
+	Methods       map[string]int `json:"methods"`
+	StatusClasses map[string]int `json:"statusClasses"`
+	Domains       int            `json:"domains"`
+	TotalTime     float64        `json:"totalTime"`
+	TotalSize     int64          `json:"totalSize"`
+}
+
+// 按状态码分类，0 表示请求未完成
+func statusClass(status int) string {
+	if status <= 0 {
+		return "failed"
+	}
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+// 生成HAR文件概要
+func summarizeHAR(file *harFile) harSummary {
+	harData := file.HAR
+	getCount, postCount, otherCount := countMethods(harData)
+	summary := harSummary{
+		File:          file.Name,
+		Size:          file.Size,
+		Version:       harData.Log.Version,
+		Creator:       strings.TrimSpace(harData.Log.Creator.Name + " " + harData.Log.Creator.Version),
+		Pages:         len(harData.Log.Pages),
+		Entries:       len(harData.Log.Entries),
+		Methods:       map[string]int{"GET": getCount, "POST": postCount, "OTHER": otherCount},
+		StatusClasses: make(map[string]int),
+		Domains:       len(extractUniqueDomains(harData)),
+	}
+	for _, entry := range harData.Log.Entries {
+		summary.StatusClasses[statusClass(entry.Response.Status)]++
+		summary.TotalTime += entry.Time
+		if entry.Response.BodySize > 0 {
+			summary.TotalSize += entry.Response.BodySize
+		}
+	}
+	return summary
+}
+
+// 从磁盘加载HAR文件，其他抓包格式会先转换为HAR
+func openHARFile(path string) (*harFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	file, err := loadCapture(filepath.Base(path), f, nil)
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", path, err)
+	}
+	return file, nil
+}
+
+// 解析参数，允许选项出现在文件名之后
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// 以缩进格式输出JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// 运行命令行模式，返回进程退出码
+func runCLI(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, cliUsage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "summary":
+		err = summaryCommand(args[1:], os.Stdout)
+	case "domains":
+		err = domainsCommand(args[1:], os.Stdout)
+	case "serve":
+		err = serveCommand(args[1:])
+	case "mock":
+		err = mockCommand(args[1:], os.Stdout)
+	case "compare":
+		err = compareCommand(args[1:], os.Stdout)
+	case "export":
+		err = exportCommand(args[1:], os.Stdout)
+	case "help", "-h", "--help":
+		fmt.Println(cliUsage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n\n%s\n", args[0], cliUsage)
+		return 2
+	}
+
+	if err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// summary 命令
+func summaryCommand(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("summary", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "以JSON格式输出")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("请指定HAR文件")
+	}
+
+	var summaries []harSummary
+	for _, path := range files {
+		file, err := openHARFile(path)
+		if err != nil {
+			return err
+		}
+		summaries = append(summaries, summarizeHAR(file))
+		file.Close()
+	}
+
+	if *jsonOutput {
+		return writeJSON(w, summaries)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, summary := range summaries {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		classes := make([]string, 0, len(summary.StatusClasses))
+		for class, count := range summary.StatusClasses {
+			classes = append(classes, fmt.Sprintf("%s %d", class, count))
+		}
+		sort.Strings(classes)
+
+		fmt.Fprintf(tw, "文件:\t%s\n", summary.File)
+		fmt.Fprintf(tw, "文件大小:\t%s\n", formatFileSize(int(summary.Size)))
+		fmt.Fprintf(tw, "HAR版本:\t%s\n", summary.Version)
+		fmt.Fprintf(tw, "生成工具:\t%s\n", summary.Creator)
+		fmt.Fprintf(tw, "页面数量:\t%d\n", summary.Pages)
+		fmt.Fprintf(tw, "请求数量:\t%d (GET %d, POST %d, 其他 %d)\n", summary.Entries,
+			summary.Methods["GET"], summary.Methods["POST"], summary.Methods["OTHER"])
+		fmt.Fprintf(tw, "状态码:\t%s\n", strings.Join(classes, ", "))
+		fmt.Fprintf(tw, "域名数量:\t%d\n", summary.Domains)
+		fmt.Fprintf(tw, "总耗时:\t%.2f ms\n", summary.TotalTime)
+		fmt.Fprintf(tw, "响应总大小:\t%s\n", formatFileSize(int(summary.TotalSize)))
+	}
+	return tw.Flush()
+}
+
+// domains 命令
+func domainsCommand(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("domains", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "以JSON格式输出")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("请指定HAR文件")
+	}
+
+	// 合并所有文件中的域名
+	domainMap := make(map[string]bool)
+	for _, path := range files {
+		file, err := openHARFile(path)
+		if err != nil {
+			return err
+		}
+		for _, domain := range extractUniqueDomains(file.HAR) {
+			domainMap[domain] = true
+		}
+		file.Close()
+	}
+	domains := make([]string, 0, len(domainMap))
+	for domain := range domainMap {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	if *jsonOutput {
+		return writeJSON(w, domains)
+	}
+	for _, domain := range domains {
+		fmt.Fprintln(w, domain)
+	}
+	return nil
+}
+
+// serve 命令，不创建GUI窗口直接运行Web服务
+func serveCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	servePort := fs.String("port", port, "Web服务端口")
+	open := fs.Bool("open", false, "启动后自动打开浏览器")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	setupRoutes()
+	address := fmt.Sprintf("http://localhost:%s", *servePort)
+
+	// 预先加载命令行指定的文件
+	if len(files) > 0 {
+		sid := store.newSession()
+		defer store.closeAll(sid)
+		var fid string
+		for _, path := range files {
+			file, err := openHARFile(path)
+			if err != nil {
+				return err
+			}
+			// 会话已被清理时 addFile 会关闭文件
+			if !store.addFile(sid, file) {
+				return fmt.Errorf("加载 %s 失败: 会话已失效", path)
+			}
+			fid = file.ID
+		}
+		address += sessionURL(sid, fid)
+	}
+
+	server := newServer(*servePort, nil)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	fmt.Printf("HAR Viewer 已启动，访问地址: %s\n", address)
+	if *open {
+		openURL(address)
+	}
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("启动服务器失败: %v", err)
+	}
+	fmt.Printf("HAR Viewer 已关闭\n")
+	return nil
+}
+
+// mock 命令，退出时输出未匹配的请求
+func mockCommand(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("mock", flag.ContinueOnError)
+	mockPort := fs.String("port", "9000", "Mock服务端口")
+	ignoreQuery := fs.Bool("ignore-query", false, "匹配时不比较查询参数")
+	matchHost := fs.Bool("match-host", false, "匹配时比较主机名")
+	matchBody := fs.Bool("match-body", false, "匹配时比较请求体")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return fmt.Errorf("请指定一个HAR文件")
+	}
+
+	file, err := openHARFile(files[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	m, err := newMockServer(file, mockRules{IgnoreQuery: *ignoreQuery, MatchHost: *matchHost, MatchBody: *matchBody})
+	if err != nil {
+		return err
+	}
+	m.log = w
+	ln, err := m.listen(*mockPort)
+	if err != nil {
+		return fmt.Errorf("启动Mock服务失败: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		m.server.Close()
+	}()
+
+	fmt.Fprintf(w, "Mock服务已启动，地址: http://localhost:%s，共 %d 个请求，未匹配请求: http://localhost:%s%s\n",
+		*mockPort, len(file.HAR.Log.Entries), *mockPort, mockReportPath)
+	if err := m.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("Mock服务异常退出: %v", err)
+	}
+
+	// 输出未匹配的请求
+	status := m.status()
+	fmt.Fprintf(w, "\nMock服务已关闭，匹配 %d 次，未匹配 %d 个请求\n", status.Matched, len(status.Unmatched))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, u := range status.Unmatched {
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", u.Count, u.Method, u.URL)
+	}
+	return tw.Flush()
+}
+
+// compare 命令
+func compareCommand(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "以JSON格式输出")
+	ignoreQueryValues := fs.Bool("ignore-query-values", false, "只比较查询参数名")
+	threshold := fs.Float64("threshold", 20, "平均耗时增长的百分比阈值")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 2 {
+		return fmt.Errorf("请指定两个HAR文件")
+	}
+
+	base, err := openHARFile(files[0])
+	if err != nil {
+		return err
+	}
+	defer base.Close()
+	target, err := openHARFile(files[1])
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	result := compareHAR(base, target, compareOptions{IgnoreQueryValues: *ignoreQueryValues, Threshold: *threshold})
+	if *jsonOutput {
+		return writeJSON(w, result)
+	}
+
+	fmt.Fprintf(w, "基准: %s\n对比: %s\n", result.Base, result.Target)
+	fmt.Fprintf(w, "新增 %d 个，删除 %d 个，变化 %d 个，未变化 %d 个请求\n",
+		len(result.Added), len(result.Removed), len(result.Changed), result.Unchanged)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if result.Regressions > 0 {
+		fmt.Fprintf(tw, "\n变慢的接口 (平均耗时增长超过 %g%%):\n", *threshold)
+		for _, t := range result.Endpoints {
+			if t.Regression {
+				fmt.Fprintf(tw, "  %.2f → %.2f ms\t%+.1f%%\t%s\n", t.BaseTime, t.TargetTime, t.Percent(), t.Name)
+			}
+		}
+	}
+	if len(result.Changed) > 0 {
+		fmt.Fprintln(tw, "\n变化的请求:")
+		for _, c := range result.Changed {
+			var parts []string
+			if c.StatusChanged() {
+				parts = append(parts, fmt.Sprintf("状态码 %d → %d", c.BaseStatus, c.TargetStatus))
+			}
+			if c.SizeChanged() {
+				parts = append(parts, fmt.Sprintf("大小 %+d", c.SizeDelta()))
+			}
+			if len(c.HeaderChanges) > 0 {
+				parts = append(parts, fmt.Sprintf("头部 %d 处", len(c.HeaderChanges)))
+			}
+			fmt.Fprintf(tw, "  %s %s\t%s\n", c.Method, c.URL, strings.Join(parts, ", "))
+		}
+	}
+	for _, section := range []struct {
+		title   string
+		entries []compareEntry
+	}{{"新增的请求:", result.Added}, {"删除的请求:", result.Removed}} {
+		if len(section.entries) == 0 {
+			continue
+		}
+		fmt.Fprintln(tw, "\n"+section.title)
+		for _, e := range section.entries {
+			fmt.Fprintf(tw, "  %d\t%s %s\n", e.Status, e.Method, e.URL)
+		}
+	}
+	return tw.Flush()
+}
+
+// export 命令
+func exportCommand(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "har", "导出格式: har、postman 或 openapi")
+	query := fs.String("filter", "", "过滤条件，语法与页面相同，如 method:POST domain:api.example.com")
+	output := fs.String("o", "", "输出文件，不指定时输出到标准输出")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return fmt.Errorf("请指定一个文件")
+	}
+	if findDataExportFormat(*format) == nil {
+		return fmt.Errorf("不支持的导出格式: %s", *format)
+	}
+	filter, err := parseFilter(*query)
+	if err != nil {
+		return fmt.Errorf("解析过滤条件失败: %v", err)
+	}
+
+	file, err := openHARFile(files[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	indexes, err := filter.apply(file)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		return writeDataExport(w, file, indexes, *format)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := writeDataExport(f, file, indexes, *format); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "已导出 %d 个请求到 %s\n", len(indexes), *output)
+	return nil
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 耗时增长超过该值（毫秒）才视为变慢，避免快速请求的正常波动
+const minRegressionTime = 10
+
+// 对比时忽略的头部，这些值在每次抓包时都会变化
+var ignoredCompareHeaders = map[string]bool{
+	"date":           true,
+	"age":            true,
+	"expires":        true,
+	"last-modified":  true,
+	"etag":           true,
+	"content-length": true,
+	"cookie":         true,
+	"set-cookie":     true,
+	"x-request-id":   true,
+	"x-trace-id":     true,
+	"cf-ray":         true,
+}
+
+// 对比选项
+type compareOptions struct {
+	IgnoreQueryValues bool    `json:"ignoreQueryValues"` // 只比较查询参数名
+	Threshold         float64 `json:"threshold"`         // 平均耗时增长的百分比阈值
+}
+
+// 只存在于一个文件中的请求
+type compareEntry struct {
+	Index  int     `json:"index"`
+	Method string  `json:"method"`
+	URL    string  `json:"url"`
+	Status int     `json:"status"`
+	Time   float64 `json:"time"`
+}
+
+// 头部差异，值为空表示该侧没有此头部
+type headerChange struct {
+	Location string `json:"location"`
+	Name     string `json:"name"`
+	Base     string `json:"base"`
+	Target   string `json:"target"`
+}
+
+// 两个文件中都存在但内容不同的请求
+type entryChange struct {
+	Method        string         `json:"method"`
+	URL           string         `json:"url"`
+	BaseIndex     int            `json:"baseIndex"`
+	TargetIndex   int            `json:"targetIndex"`
+	BaseStatus    int            `json:"baseStatus"`
+	TargetStatus  int            `json:"targetStatus"`
+	BaseSize      int64          `json:"baseSize"`
+	TargetSize    int64          `json:"targetSize"`
+	HeaderChanges []headerChange `json:"headerChanges"`
+}
+
+func (c entryChange) StatusChanged() bool { return c.BaseStatus != c.TargetStatus }
+func (c entryChange) SizeChanged() bool   { return c.BaseSize != c.TargetSize }
+func (c entryChange) SizeDelta() int64    { return c.TargetSize - c.BaseSize }
+
+// 按接口或域名统计的耗时对比
+type timingChange struct {
+	Name        string  `json:"name"`
+	BaseCount   int     `json:"baseCount"`
+	TargetCount int     `json:"targetCount"`
+	BaseTime    float64 `json:"baseTime"` // 平均耗时
+	TargetTime  float64 `json:"targetTime"`
+	BaseBytes   int64   `json:"baseBytes"`
+	TargetBytes int64   `json:"targetBytes"`
+	Regression  bool    `json:"regression"`
+}
+
+func (t timingChange) Delta() float64 { return t.TargetTime - t.BaseTime }
+
+// 耗时变化百分比，基准耗时为 0 时返回 0
+func (t timingChange) Percent() float64 {
+	if t.BaseTime <= 0 {
+		return 0
+	}
+	return (t.TargetTime - t.BaseTime) / t.BaseTime * 100
+}
+
+// 对比结果
+type compareResult struct {
+	Base        string         `json:"base"`
+	Target      string         `json:"target"`
+	Options     compareOptions `json:"options"`
+	Unchanged   int            `json:"unchanged"`
+	Added       []compareEntry `json:"added"`
+	Removed     []compareEntry `json:"removed"`
+	Changed     []entryChange  `json:"changed"`
+	Endpoints   []timingChange `json:"endpoints"`
+	Domains     []timingChange `json:"domains"`
+	Regressions int            `json:"regressions"`
+}
+
+// 规范化URL：主机名小写、去掉默认端口和锚点、查询参数按名称排序
+func normalizeURL(rawURL string, ignoreQueryValues bool) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if p := u.Port(); p != "" && !(u.Scheme == "http" && p == "80") && !(u.Scheme == "https" && p == "443") {
+		host += ":" + p
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	values := u.Query()
+	if ignoreQueryValues {
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, url.QueryEscape(name))
+		}
+		sort.Strings(names)
+		u.RawQuery = strings.Join(names, "&")
+	} else {
+		u.RawQuery = values.Encode()
+	}
+	return u.String()
+}
+
+// 按名称合并头部，名称小写，多个值用换行连接
+func headerMap(headers []Header) map[string]string {
+	m := make(map[string]string)
+	for _, header := range headers {
+		name := strings.ToLower(header.Name)
+		if strings.HasPrefix(name, ":") || ignoredCompareHeaders[name] {
+			continue
+		}
+		if value, ok := m[name]; ok {
+			m[name] = value + "\n" + header.Value
+		} else {
+			m[name] = header.Value
+		}
+	}
+	return m
+}
+
+// 对比两组头部
+func diffHeaders(location string, base, target []Header) []headerChange {
+	baseMap, targetMap := headerMap(base), headerMap(target)
+	var changes []headerChange
+	for name, value := range baseMap {
+		if targetMap[name] != value {
+			changes = append(changes, headerChange{Location: location, Name: name, Base: value, Target: targetMap[name]})
+		}
+	}
+	for name, value := range targetMap {
+		if _, ok := baseMap[name]; !ok {
+			changes = append(changes, headerChange{Location: location, Name: name, Target: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// 累计耗时和大小，平均值在 finish 中计算
+type timingAccumulator struct {
+	changes map[string]*timingChange
+}
+
+func (a *timingAccumulator) add(name string, entry *Entry, target bool) {
+	t, ok := a.changes[name]
+	if !ok {
+		t = &timingChange{Name: name}
+		a.changes[name] = t
+	}
+	size := max(entry.Response.Content.Size, 0)
+	if target {
+		t.TargetCount++
+		t.TargetTime += max(entry.Time, 0)
+		t.TargetBytes += size
+	} else {
+		t.BaseCount++
+		t.BaseTime += max(entry.Time, 0)
+		t.BaseBytes += size
+	}
+}
+
+// 计算平均耗时并标记变慢的项，结果按耗时增长从大到小排列
+func (a *timingAccumulator) finish(threshold float64) ([]timingChange, int) {
+	result := make([]timingChange, 0, len(a.changes))
+	regressions := 0
+	for _, t := range a.changes {
+		if t.BaseCount > 0 {
+			t.BaseTime /= float64(t.BaseCount)
+		}
+		if t.TargetCount > 0 {
+			t.TargetTime /= float64(t.TargetCount)
+		}
+		if t.BaseCount > 0 && t.TargetCount > 0 && t.Delta() >= minRegressionTime && t.Percent() >= threshold {
+			t.Regression = true
+			regressions++
+		}
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Delta() != result[j].Delta() {
+			return result[i].Delta() > result[j].Delta()
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, regressions
+}
+
+// 对比两个HAR文件，同一请求出现多次时按出现顺序一一对应
+func compareHAR(base, target *harFile, options compareOptions) compareResult {
+	result := compareResult{Base: base.Name, Target: target.Name, Options: options}
+	endpoints := &timingAccumulator{changes: make(map[string]*timingChange)}
+	domains := &timingAccumulator{changes: make(map[string]*timingChange)}
+
+	key := func(entry *Entry) string {
+		return strings.ToUpper(entry.Request.Method) + " " + normalizeURL(entry.Request.URL, options.IgnoreQueryValues)
+	}
+	// 接口耗时按请求方法、主机名和路径模板汇总，路径中的ID替换为 {id}
+	endpointKey := func(entry *Entry) string {
+		method := strings.ToUpper(entry.Request.Method)
+		if host, template, ok := endpointTemplate(normalizeURL(entry.Request.URL, true)); ok {
+			return method + " " + host + template
+		}
+		return method + " " + entry.Request.URL
+	}
+	newCompareEntry := func(index int, entry *Entry) compareEntry {
+		return compareEntry{Index: index, Method: entry.Request.Method, URL: entry.Request.URL, Status: entry.Response.Status, Time: entry.Time}
+	}
+
+	targetIndexes := make(map[string][]int)
+	targetEntries := target.HAR.Log.Entries
+	for i := range targetEntries {
+		entry := &targetEntries[i]
+		k := key(entry)
+		targetIndexes[k] = append(targetIndexes[k], i)
+		endpoints.add(endpointKey(entry), entry, true)
+		domains.add(extractDomain(entry.Request.URL), entry, true)
+	}
+
+	matched := make([]bool, len(targetEntries))
+	used := make(map[string]int)
+	for i := range base.HAR.Log.Entries {
+		entry := &base.HAR.Log.Entries[i]
+		k := key(entry)
+		endpoints.add(endpointKey(entry), entry, false)
+		domains.add(extractDomain(entry.Request.URL), entry, false)
+
+		indexes := targetIndexes[k]
+		if used[k] >= len(indexes) {
+			result.Removed = append(result.Removed, newCompareEntry(i, entry))
+			continue
+		}
+		j := indexes[used[k]]
+		used[k]++
+		matched[j] = true
+
+		other := &targetEntries[j]
+		change := entryChange{
+			Method:       entry.Request.Method,
+			URL:          entry.Request.URL,
+			BaseIndex:    i,
+			TargetIndex:  j,
+			BaseStatus:   entry.Response.Status,
+			TargetStatus: other.Response.Status,
+			BaseSize:     entry.Response.Content.Size,
+			TargetSize:   other.Response.Content.Size,
+		}
+		change.HeaderChanges = append(diffHeaders("请求头", entry.Request.Headers, other.Request.Headers),
+			diffHeaders("响应头", entry.Response.Headers, other.Response.Headers)...)
+		if change.StatusChanged() || change.SizeChanged() || len(change.HeaderChanges) > 0 {
+			result.Changed = append(result.Changed, change)
+		} else {
+			result.Unchanged++
+		}
+	}
+	for j := range targetEntries {
+		if !matched[j] {
+			result.Added = append(result.Added, newCompareEntry(j, &targetEntries[j]))
+		}
+	}
+
+	var regressions int
+	result.Endpoints, regressions = endpoints.finish(options.Threshold)
+	result.Regressions += regressions
+	result.Domains, _ = domains.finish(options.Threshold)
+	return result
+}
+
+// 从请求参数读取对比选项
+func parseCompareOptions(r *http.Request) compareOptions {
+	options := compareOptions{IgnoreQueryValues: r.FormValue("ignoreQueryValues") != "", Threshold: 20}
+	if value, err := strconv.ParseFloat(r.FormValue("threshold"), 64); err == nil && value >= 0 {
+		options.Threshold = value
+	}
+	return options
+}
+
+var compareTemplate = `<div class="compare-result">
+    <p>基准: <strong>{{.Base}}</strong> → 对比: <strong>{{.Target}}</strong></p>
+    <p>新增 {{len .Added}} 个，删除 {{len .Removed}} 个，变化 {{len .Changed}} 个，未变化 {{.Unchanged}} 个请求；{{.Regressions}} 个接口平均耗时增长超过 {{.Options.Threshold}}%</p>
+
+    <details open>
+        <summary>接口耗时 ({{len .Endpoints}})</summary>
+        <table class="param-table">
+            <tr><th>接口</th><th>次数</th><th>平均耗时</th><th>变化</th><th>响应大小</th></tr>
+            {{range .Endpoints}}
+            <tr{{if .Regression}} class="compare-regression"{{end}}>
+                <td>{{.Name}}</td>
+                <td>{{.BaseCount}} → {{.TargetCount}}</td>
+                <td>{{printf "%.2f" .BaseTime}} → {{printf "%.2f" .TargetTime}} ms</td>
+                <td>{{if and .BaseCount .TargetCount}}{{printf "%+.2f" .Delta}} ms ({{printf "%+.1f" .Percent}}%){{end}}</td>
+                <td>{{.BaseBytes}} → {{.TargetBytes}}</td>
+            </tr>
+            {{end}}
+        </table>
+    </details>
+
+    <details>
+        <summary>域名耗时 ({{len .Domains}})</summary>
+        <table class="param-table">
+            <tr><th>域名</th><th>次数</th><th>平均耗时</th><th>变化</th><th>响应大小</th></tr>
+            {{range .Domains}}
+            <tr{{if .Regression}} class="compare-regression"{{end}}>
+                <td>{{.Name}}</td>
+                <td>{{.BaseCount}} → {{.TargetCount}}</td>
+                <td>{{printf "%.2f" .BaseTime}} → {{printf "%.2f" .TargetTime}} ms</td>
+                <td>{{if and .BaseCount .TargetCount}}{{printf "%+.2f" .Delta}} ms ({{printf "%+.1f" .Percent}}%){{end}}</td>
+                <td>{{.BaseBytes}} → {{.TargetBytes}}</td>
+            </tr>
+            {{end}}
+        </table>
+    </details>
+
+    <details{{if .Changed}} open{{end}}>
+        <summary>变化的请求 ({{len .Changed}})</summary>
+        <table class="param-table">
+            <tr><th>序号</th><th>请求</th><th>状态码</th><th>响应大小</th><th>头部差异</th></tr>
+            {{range .Changed}}
+            <tr>
+                <td>{{.BaseIndex}} → {{.TargetIndex}}</td>
+                <td>{{.Method}} {{.URL}}</td>
+                <td{{if .StatusChanged}} class="compare-regression"{{end}}>{{.BaseStatus}}{{if .StatusChanged}} → {{.TargetStatus}}{{end}}</td>
+                <td>{{.BaseSize}}{{if .SizeChanged}} → {{.TargetSize}} ({{printf "%+d" .SizeDelta}}){{end}}</td>
+                <td>
+                    {{range .HeaderChanges}}<div><strong>{{.Location}} {{.Name}}:</strong> {{if .Base}}{{.Base}}{{else}}(无){{end}} → {{if .Target}}{{.Target}}{{else}}(无){{end}}</div>{{end}}
+                </td>
+            </tr>
+            {{end}}
+        </table>
+    </details>
+
+    <details{{if .Added}} open{{end}}>
+        <summary>新增的请求 ({{len .Added}})</summary>
+        <table class="param-table">
+            <tr><th>序号</th><th>请求</th><th>状态码</th><th>耗时</th></tr>
+            {{range .Added}}<tr><td>{{.Index}}</td><td>{{.Method}} {{.URL}}</td><td>{{.Status}}</td><td>{{printf "%.2f" .Time}} ms</td></tr>{{end}}
+        </table>
+    </details>
+
+    <details{{if .Removed}} open{{end}}>
+        <summary>删除的请求 ({{len .Removed}})</summary>
+        <table class="param-table">
+            <tr><th>序号</th><th>请求</th><th>状态码</th><th>耗时</th></tr>
+            {{range .Removed}}<tr><td>{{.Index}}</td><td>{{.Method}} {{.URL}}</td><td>{{.Status}}</td><td>{{printf "%.2f" .Time}} ms</td></tr>{{end}}
+        </table>
+    </details>
+</div>`
+
+// 对比处理函数，fid 为基准文件，target 为对比文件
+func compareHandler(w http.ResponseWriter, r *http.Request) {
+	sid, base, ok := fileFromRequest(w, r)
+	if !ok {
+		return
+	}
+	target := store.acquireFile(r, sid, r.FormValue("target"))
+	if target == nil {
+		http.Error(w, "请选择要对比的文件", http.StatusBadRequest)
+		return
+	}
+
+	tmpl, err := template.New("compare").Parse(compareTemplate)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("解析模板失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+	tmpl.Execute(w, compareHAR(base, target, parseCompareOptions(r)))
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// 响应设置的 Cookie 及其属性
+type setCookie struct {
+	Name     string
+	Value    string
+	Domain   string // 未指定时为请求的主机名（仅限该主机）
+	HostOnly bool
+	Path     string
+	Expires  string // 为空表示会话 Cookie
+	HTTPOnly bool
+	Secure   bool
+	SameSite string
+	Deleted  bool // Max-Age<=0 或过期时间早于响应时间
+	Issues   []string
+}
+
+// 请求发送的 Cookie
+type sentCookie struct {
+	Name   string
+	Value  string
+	Issues []string
+}
+
+// Cookie 生命周期中的一次发送或设置
+type cookieEvent struct {
+	Index   int
+	Kind    string // 设置、更新、删除、发送
+	Value   string
+	URL     string
+	Started string
+	Issues  []string
+}
+
+// 按名称、域名和路径汇总的 Cookie
+type cookieSummary struct {
+	Name     string
+	Domain   string
+	Path     string
+	Expires  string
+	HTTPOnly bool
+	Secure   bool
+	SameSite string
+	Sent     int
+	Set      int
+	Values   int // 不同值的数量
+	Issues   []string
+	Events   []cookieEvent
+	hostOnly bool
+	values   map[string]bool
+}
+
+// 将 SameSite 属性转换为文本
+func sameSiteText(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return ""
+}
+
+// 追加不重复的问题
+func addIssue(issues []string, issue string) []string {
+	for _, existing := range issues {
+		if existing == issue {
+			return issues
+		}
+	}
+	return append(issues, issue)
+}
+
+// 检查 Cookie 属性中的安全问题
+func cookieIssues(c *setCookie, https bool) []string {
+	var issues []string
+	if c.Deleted {
+		return issues
+	}
+	if !c.Secure {
+		issues = append(issues, "未设置 Secure")
+	}
+	if !https && c.Secure {
+		issues = append(issues, "通过 HTTP 设置了 Secure Cookie")
+	}
+	if !c.HTTPOnly {
+		issues = append(issues, "未设置 HttpOnly，脚本可读取")
+	}
+	switch c.SameSite {
+	case "":
+		issues = append(issues, "未设置 SameSite")
+	case "None":
+		if !c.Secure {
+			issues = append(issues, "SameSite=None 但未设置 Secure，浏览器会拒绝")
+		}
+	}
+	if strings.HasPrefix(c.Name, "__Secure-") && !c.Secure {
+		issues = append(issues, "__Secure- 前缀要求 Secure")
+	}
+	if strings.HasPrefix(c.Name, "__Host-") && (!c.Secure || !c.HostOnly || c.Path != "/") {
+		issues = append(issues, "__Host- 前缀要求 Secure、Path=/ 且不设置 Domain")
+	}
+	return issues
+}
+
+// 获取响应设置的 Cookie，优先解析 Set-Cookie 头（属性更完整），没有时使用 cookies 字段
+func entrySetCookies(entry *Entry) []setCookie {
+	u, _ := url.Parse(entry.Request.URL)
+	var host string
+	https := false
+	if u != nil {
+		host = strings.ToLower(u.Hostname())
+		https = u.Scheme == "https"
+	}
+	started, _ := parseHARTime(entry.StartedDateTime)
+
+	var cookies []setCookie
+	for _, header := range entry.Response.Headers {
+		if !strings.EqualFold(header.Name, "Set-Cookie") {
+			continue
+		}
+		// 部分工具将多个 Set-Cookie 合并为一个以换行分隔的头
+		for _, line := range strings.Split(header.Value, "\n") {
+			parsed, err := http.ParseSetCookie(strings.TrimSpace(line))
+			if err != nil {
+				continue
+			}
+			c := setCookie{
+				Name:     parsed.Name,
+				Value:    parsed.Value,
+				Domain:   strings.TrimPrefix(strings.ToLower(parsed.Domain), "."),
+				Path:     parsed.Path,
+				Expires:  parsed.RawExpires,
+				HTTPOnly: parsed.HttpOnly,
+				Secure:   parsed.Secure,
+				SameSite: sameSiteText(parsed.SameSite),
+				Deleted:  parsed.MaxAge < 0,
+			}
+			if parsed.MaxAge > 0 {
+				c.Expires = fmt.Sprintf("Max-Age=%d", parsed.MaxAge)
+			}
+			if !parsed.Expires.IsZero() && !started.IsZero() && parsed.Expires.Before(started) {
+				c.Deleted = true
+			}
+			cookies = append(cookies, c)
+		}
+	}
+
+	if len(cookies) == 0 {
+		for _, cookie := range entry.Response.Cookies {
+			c := setCookie{
+				Name:     cookie.Name,
+				Value:    cookie.Value,
+				Domain:   strings.TrimPrefix(strings.ToLower(cookie.Domain), "."),
+				Path:     cookie.Path,
+				Expires:  cookie.Expires,
+				HTTPOnly: cookie.HTTPOnly,
+				Secure:   cookie.Secure,
+				SameSite: cookie.SameSite,
+			}
+			if expires, ok := parseHARTime(cookie.Expires); ok && !started.IsZero() && expires.Before(started) {
+				c.Deleted = true
+			}
+			cookies = append(cookies, c)
+		}
+	}
+
+	for i := range cookies {
+		c := &cookies[i]
+		if c.Domain == "" {
+			c.Domain, c.HostOnly = host, true
+		}
+		if c.Path == "" {
+			c.Path = "/"
+		}
+		c.Issues = cookieIssues(c, https)
+	}
+	return cookies
+}
+
+// 获取请求发送的 Cookie，没有 cookies 字段时解析 Cookie 请求头
+func entrySentCookies(entry *Entry) []sentCookie {
+	var cookies []sentCookie
+	for _, cookie := range entry.Request.Cookies {
+		cookies = append(cookies, sentCookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	if len(cookies) == 0 {
+		for _, header := range entry.Request.Headers {
+			if !strings.EqualFold(header.Name, "Cookie") {
+				continue
+			}
+			parsed, err := http.ParseCookie(header.Value)
+			if err != nil {
+				continue
+			}
+			for _, cookie := range parsed {
+				cookies = append(cookies, sentCookie{Name: cookie.Name, Value: cookie.Value})
+			}
+		}
+	}
+	if strings.HasPrefix(strings.ToLower(entry.Request.URL), "http://") {
+		for i := range cookies {
+			cookies[i].Issues = []string{"通过 HTTP 明文发送"}
+		}
+	}
+	return cookies
+}
+
+// 判断主机名是否属于 Cookie 的域名
+func domainMatch(host, domain string, hostOnly bool) bool {
+	if hostOnly {
+		return host == domain
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// 判断请求路径是否匹配 Cookie 的路径
+func pathMatch(path, cookiePath string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if cookiePath == "/" || path == cookiePath {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(cookiePath, "/")+"/")
+}
+
+// 按条目顺序汇总所有 Cookie 的设置和发送记录
+// 发送的 Cookie 没有域名信息，匹配之前设置过的同名 Cookie，找不到时按请求的主机名归类
+func collectCookies(harData *HAR) []*cookieSummary {
+	summaries := make(map[string]*cookieSummary)
+	var ordered []*cookieSummary
+	get := func(name, domain, path string) *cookieSummary {
+		key := name + "\x00" + domain + "\x00" + path
+		summary, ok := summaries[key]
+		if !ok {
+			summary = &cookieSummary{Name: name, Domain: domain, Path: path, values: make(map[string]bool)}
+			summaries[key] = summary
+			ordered = append(ordered, summary)
+		}
+		return summary
+	}
+
+	// 设置过的 Cookie 按名称和域名索引，发送的 Cookie 只需查找请求的主机名及其上级域名
+	setByDomain := make(map[string][]*cookieSummary)
+
+	// 按开始时间处理，保证生命周期顺序正确
+	entries := harData.Log.Entries
+	indexes := make([]int, len(entries))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sortByStartTime(entries, indexes)
+
+	for _, i := range indexes {
+		entry := &entries[i]
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+
+		for _, c := range entrySentCookies(entry) {
+			// 选择匹配的 Cookie 中域名和路径最具体的一个
+			var summary *cookieSummary
+			for domain := host; ; {
+				for _, s := range setByDomain[c.Name+"\x00"+domain] {
+					if !domainMatch(host, s.Domain, s.hostOnly) || !pathMatch(u.Path, s.Path) {
+						continue
+					}
+					if summary == nil || len(s.Domain)+len(s.Path) > len(summary.Domain)+len(summary.Path) {
+						summary = s
+					}
+				}
+				dot := strings.IndexByte(domain, '.')
+				if dot < 0 {
+					break
+				}
+				domain = domain[dot+1:]
+			}
+			if summary == nil {
+				summary = get(c.Name, host, "")
+			}
+			summary.Sent++
+			summary.values[c.Value] = true
+			for _, issue := range c.Issues {
+				summary.Issues = addIssue(summary.Issues, issue)
+			}
+			summary.Events = append(summary.Events, cookieEvent{Index: i, Kind: "发送", Value: c.Value, URL: entry.Request.URL, Started: entry.StartedDateTime, Issues: c.Issues})
+		}
+
+		for _, c := range entrySetCookies(entry) {
+			summary := get(c.Name, c.Domain, c.Path)
+			kind := "设置"
+			switch {
+			case c.Deleted:
+				kind = "删除"
+			case summary.Set > 0:
+				kind = "更新"
+			}
+			if summary.Set == 0 {
+				key := c.Name + "\x00" + c.Domain
+				setByDomain[key] = append(setByDomain[key], summary)
+			}
+			summary.Set++
+			if !c.Deleted {
+				summary.values[c.Value] = true
+				summary.Expires, summary.HTTPOnly, summary.Secure, summary.SameSite = c.Expires, c.HTTPOnly, c.Secure, c.SameSite
+				summary.hostOnly = c.HostOnly
+			}
+			for _, issue := range c.Issues {
+				summary.Issues = addIssue(summary.Issues, issue)
+			}
+			summary.Events = append(summary.Events, cookieEvent{Index: i, Kind: kind, Value: c.Value, URL: entry.Request.URL, Started: entry.StartedDateTime, Issues: c.Issues})
+		}
+	}
+
+	for _, summary := range ordered {
+		summary.Values = len(summary.values)
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Domain != ordered[j].Domain {
+			return ordered[i].Domain < ordered[j].Domain
+		}
+		return ordered[i].Name < ordered[j].Name
+	})
+	return ordered
+}
+
+var cookiesTemplate = `<div class="cookie-view">
+    {{if not .}}
+    <p>没有发送或设置 Cookie</p>
+    {{else}}
+    <p>共 {{len .}} 个 Cookie，点击名称查看每次发送和设置的记录</p>
+    <table class="param-table">
+        <tr><th>名称</th><th>域名</th><th>路径</th><th>过期时间</th><th>HttpOnly</th><th>Secure</th><th>SameSite</th><th>发送/设置</th><th>问题</th></tr>
+        {{range .}}
+        <tr>
+            <td>
+                <details>
+                    <summary>{{.Name}}</summary>
+                    <table class="param-table">
+                        <tr><th>序号</th><th>操作</th><th>值</th><th>URL</th></tr>
+                        {{range .Events}}
+                        <tr{{if .Issues}} class="cookie-issue"{{end}}><td>{{.Index}}</td><td>{{.Kind}}</td><td class="cookie-value">{{.Value}}</td><td>{{.URL}}</td></tr>
+                        {{end}}
+                    </table>
+                </details>
+            </td>
+            <td>{{.Domain}}</td>
+            <td>{{.Path}}</td>
+            <td>{{if .Set}}{{if .Expires}}{{.Expires}}{{else}}会话{{end}}{{end}}</td>
+            <td>{{if .Set}}{{if .HTTPOnly}}✓{{else}}✗{{end}}{{end}}</td>
+            <td>{{if .Set}}{{if .Secure}}✓{{else}}✗{{end}}{{end}}</td>
+            <td>{{.SameSite}}</td>
+            <td>{{.Sent}} / {{.Set}}{{if gt .Values 1}}（{{.Values}} 个不同值）{{end}}</td>
+            <td>{{range .Issues}}<div class="cookie-issue">{{.}}</div>{{end}}</td>
+        </tr>
+        {{end}}
+    </table>
+    {{end}}
+</div>`
+
+// Cookie 汇总处理函数，返回HTML片段
+func cookiesHandler(w http.ResponseWriter, r *http.Request) {
+	_, file, ok := fileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tmpl, err := template.New("cookies").Parse(cookiesTemplate)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("解析模板失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+	tmpl.Execute(w, collectCookies(file.HAR))
+}
+
+// 条目详情中的 Cookie 部分
+var entryCookiesTemplate = `{{if or .SentCookies .SetCookies}}
+    <h4>Cookie</h4>
+    {{if .SentCookies}}
+    <table class="param-table">
+        <tr><th>发送的 Cookie</th><th>值</th></tr>
+        {{range .SentCookies}}<tr><td>{{.Name}}</td><td class="cookie-value">{{.Value}}</td></tr>{{end}}
+    </table>
+    {{end}}
+    {{if .SetCookies}}
+    <table class="param-table">
+        <tr><th>设置的 Cookie</th><th>值</th><th>域名</th><th>路径</th><th>过期时间</th><th>属性</th><th>问题</th></tr>
+        {{range .SetCookies}}
+        <tr>
+            <td>{{.Name}}{{if .Deleted}}（删除）{{end}}</td>
+            <td class="cookie-value">{{.Value}}</td>
+            <td>{{.Domain}}</td>
+            <td>{{.Path}}</td>
+            <td>{{if .Expires}}{{.Expires}}{{else}}会话{{end}}</td>
+            <td>{{if .HTTPOnly}}HttpOnly {{end}}{{if .Secure}}Secure {{end}}{{if .SameSite}}SameSite={{.SameSite}}{{end}}</td>
+            <td>{{range .Issues}}<div class="cookie-issue">{{.}}</div>{{end}}</td>
+        </tr>
+        {{end}}
+    </table>
+    {{end}}
+{{end}}`
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// 创建设置或发送 Cookie 的条目
+func cookieTestEntry(started, rawURL, cookie, setCookie string) Entry {
+	entry := Entry{StartedDateTime: started, Request: Request{Method: "GET", URL: rawURL}}
+	if cookie != "" {
+		entry.Request.Headers = []Header{{Name: "Cookie", Value: cookie}}
+	}
+	if setCookie != "" {
+		entry.Response.Headers = []Header{{Name: "Set-Cookie", Value: setCookie}}
+	}
+	return entry
+}
+
+// 汇总结果：名称 域名 路径 设置次数 发送次数 事件
+func cookieSummaryText(summaries []*cookieSummary) []string {
+	var texts []string
+	for _, s := range summaries {
+		var events []string
+		for _, event := range s.Events {
+			events = append(events, fmt.Sprintf("%s#%d", event.Kind, event.Index))
+		}
+		texts = append(texts, fmt.Sprintf("%s %s %s set=%d sent=%d %s", s.Name, s.Domain, s.Path, s.Set, s.Sent, strings.Join(events, ",")))
+	}
+	return texts
+}
+
+// 按解析后的时间排序，时区不同时字符串顺序与时间顺序不一致
+func TestCollectCookiesOrder(t *testing.T) {
+	harData := &HAR{Log: Log{Entries: []Entry{
+		cookieTestEntry("2024-01-01T03:00:00Z", "https://example.com/a", "sid=v1", ""),
+		cookieTestEntry("2024-01-01T10:00:00+08:00", "https://example.com/login", "", "sid=v1; Path=/; Secure; HttpOnly; SameSite=Lax"),
+	}}}
+	got := cookieSummaryText(collectCookies(harData))
+	want := []string{"sid example.com / set=1 sent=1 设置#1,发送#0"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("汇总为 %q，应为 %q", got, want)
+	}
+}
+
+// 发送的 Cookie 匹配域名和路径最具体的一个，仅限主机的 Cookie 不匹配子域名
+func TestCollectCookiesMatch(t *testing.T) {
+	harData := &HAR{Log: Log{Entries: []Entry{
+		cookieTestEntry("2024-01-01T00:00:01Z", "https://example.com/", "", "sid=root; Domain=example.com; Path=/"),
+		cookieTestEntry("2024-01-01T00:00:02Z", "https://api.example.com/v1/login", "", "sid=api; Domain=api.example.com; Path=/v1"),
+		cookieTestEntry("2024-01-01T00:00:03Z", "https://example.com/", "", "host=1; Path=/"),
+		cookieTestEntry("2024-01-01T00:00:04Z", "https://api.example.com/v1/users", "sid=api; host=1", ""),
+		cookieTestEntry("2024-01-01T00:00:05Z", "https://www.example.com/", "sid=root", ""),
+		cookieTestEntry("2024-01-01T00:00:06Z", "https://api.example.com/v2", "sid=root", ""),
+		cookieTestEntry("2024-01-01T00:00:07Z", "https://example.com/", "host=1", ""),
+	}}}
+	got := cookieSummaryText(collectCookies(harData))
+	want := []string{
+		"host api.example.com  set=0 sent=1 发送#3",
+		"sid api.example.com /v1 set=1 sent=1 设置#1,发送#3",
+		"host example.com / set=1 sent=1 设置#2,发送#6",
+		"sid example.com / set=1 sent=2 设置#0,发送#4,发送#5",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("汇总为\n%s\n应为\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// 每个接口列出的请求数量，其余的在请求列表中查看
+const maxEndpointMembers = 20
+
+// 接口中某类状态码的数量
+type endpointStatus struct {
+	Class   string  `json:"class"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"-"`
+}
+
+// 按接口模板统计的结果
+type endpointStats struct {
+	Method    string           `json:"method"`
+	Host      string           `json:"host"`
+	Template  string           `json:"template"`
+	Count     int              `json:"count"`
+	URLs      int              `json:"urls"` // 不同URL的数量
+	Errors    int              `json:"errors"`
+	Statuses  []endpointStatus `json:"statuses"`
+	Average   float64          `json:"average"`
+	P50       float64          `json:"p50"`
+	P90       float64          `json:"p90"`
+	P99       float64          `json:"p99"`
+	Max       float64          `json:"max"`
+	Bytes     int64            `json:"bytes"`
+	Entries   []int            `json:"entries"`
+	Filter    string           `json:"filter"` // 筛选该接口请求的过滤条件
+	FilterURL string           `json:"-"`
+}
+
+// 接口的名称，如 api.example.com/users/{id}
+func (e *endpointStats) Name() string {
+	return e.Host + e.Template
+}
+
+// 错误率（4xx、5xx 和失败的请求）
+func (e *endpointStats) ErrorPercent() float64 {
+	if e.Count == 0 {
+		return 0
+	}
+	return float64(e.Errors) / float64(e.Count) * 100
+}
+
+// 列出的请求，最多 maxEndpointMembers 个
+func (e *endpointStats) Members() []int {
+	return e.Entries[:min(len(e.Entries), maxEndpointMembers)]
+}
+
+// 归一化URL的路径，将数字、UUID、哈希等ID替换为 {id}，返回主机名和路径模板
+func endpointTemplate(rawURL string) (string, string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "", "", false
+	}
+	template := normalizePath(u.EscapedPath(), func(string, string, string) string {
+		return "{id}"
+	})
+	return u.Host, template, true
+}
+
+// 生成筛选接口请求的过滤条件，模板中可能包含空格和引号
+func endpointFilter(method, name string) string {
+	return quoteFilterTerm("method:"+method) + " " + quoteFilterTerm("endpoint:"+name)
+}
+
+// 按请求方法和接口模板聚合条目，sortKey 为 count、p90、max、errors 或 bytes
+func buildEndpointStats(harData *HAR, indexes []int, sortKey string) []*endpointStats {
+	var endpoints []*endpointStats
+	byKey := make(map[string]*endpointStats)
+	times := make(map[*endpointStats][]float64)
+	urls := make(map[*endpointStats]map[string]bool)
+	for _, index := range indexes {
+		entry := &harData.Log.Entries[index]
+		host, template, ok := endpointTemplate(entry.Request.URL)
+		if !ok {
+			continue
+		}
+		key := entry.Request.Method + " " + host + template
+		endpoint := byKey[key]
+		if endpoint == nil {
+			endpoint = &endpointStats{Method: entry.Request.Method, Host: host, Template: template}
+			byKey[key] = endpoint
+			urls[endpoint] = make(map[string]bool)
+			endpoints = append(endpoints, endpoint)
+		}
+		endpoint.Count++
+		endpoint.Entries = append(endpoint.Entries, index)
+		endpoint.Bytes += max(entry.Response.Content.Size, 0)
+		if class := statusClass(entry.Response.Status); class == "4xx" || class == "5xx" || class == "failed" {
+			endpoint.Errors++
+		}
+		times[endpoint] = append(times[endpoint], max(entry.Time, 0))
+		urls[endpoint][entry.Request.URL] = true
+	}
+
+	for _, endpoint := range endpoints {
+		values := times[endpoint]
+		sort.Float64s(values)
+		var total float64
+		for _, value := range values {
+			total += value
+		}
+		endpoint.Average = total / float64(len(values))
+		endpoint.P50 = percentileValue(values, 50)
+		endpoint.P90 = percentileValue(values, 90)
+		endpoint.P99 = percentileValue(values, 99)
+		endpoint.Max = percentileValue(values, 100)
+		endpoint.URLs = len(urls[endpoint])
+		endpoint.Filter = endpointFilter(endpoint.Method, endpoint.Name())
+
+		// 状态码按类别排列，如 2xx、3xx、4xx
+		counts := make(map[string]int)
+		for _, index := range endpoint.Entries {
+			counts[statusClass(harData.Log.Entries[index].Response.Status)]++
+		}
+		for class, count := range counts {
+			endpoint.Statuses = append(endpoint.Statuses, endpointStatus{
+				Class: class, Count: count, Percent: float64(count) / float64(endpoint.Count) * 100,
+			})
+		}
+		sort.Slice(endpoint.Statuses, func(i, j int) bool { return endpoint.Statuses[i].Class < endpoint.Statuses[j].Class })
+	}
+
+	value := func(e *endpointStats) float64 {
+		switch sortKey {
+		case "p90":
+			return e.P90
+		case "max":
+			return e.Max
+		case "errors":
+			return float64(e.Errors)
+		case "bytes":
+			return float64(e.Bytes)
+		}
+		return float64(e.Count)
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		a, b := value(endpoints[i]), value(endpoints[j])
+		if a != b {
+			return a > b
+		}
+		return endpoints[i].Count > endpoints[j].Count
+	})
+	return endpoints
+}
+
+var endpointsTemplate = `<div class="endpoint-view">
+    {{if not .Endpoints}}
+    <p>没有可以归类的请求</p>
+    {{else}}
+    <p>{{if .Query}}符合过滤条件的 {{end}}{{.Total}} 个请求归为 {{len .Endpoints}} 个接口，路径中的数字、UUID、哈希等ID替换为 {id}，点击接口查看其中的请求</p>
+    <table class="param-table endpoint-table">
+        <tr>
+            <th>方法</th>
+            <th>接口</th>
+            {{range .Columns}}<th>{{if eq .Key $.Sort}}{{.Title}} ▼{{else}}<a href="#" onclick="return reloadLazySection(this, '{{.URL}}')">{{.Title}}</a>{{end}}</th>{{end}}
+            <th>状态码</th>
+            <th>平均 / p50 / p99 (ms)</th>
+        </tr>
+        {{range .Endpoints}}
+        <tr>
+            <td>{{.Method}}</td>
+            <td class="endpoint-name">
+                <details>
+                    <summary title="{{.URLs}} 个不同的URL">{{.Name}}</summary>
+                    <table class="param-table">
+                        <tr><th>序号</th><th>状态码</th><th>耗时 (ms)</th><th>URL</th></tr>
+                        {{range .Members}}{{with index $.Entries .}}
+                        <tr><td>#{{.Index}}</td><td>{{.Status}}</td><td>{{printf "%.2f" .Time}}</td><td class="param-value">{{.URL}}</td></tr>
+                        {{end}}{{end}}
+                    </table>
+                    <a href="{{.FilterURL}}">在请求列表中查看全部 {{.Count}} 个请求</a>
+                </details>
+            </td>
+            <td>{{.Count}}</td>
+            <td>{{printf "%.2f" .P90}}</td>
+            <td>{{printf "%.2f" .Max}}</td>
+            <td{{if .Errors}} class="endpoint-error"{{end}}>{{.Errors}}{{if .Errors}} ({{printf "%.1f" .ErrorPercent}}%){{end}}</td>
+            <td>{{formatSize .Bytes}}</td>
+            <td>
+                <div class="endpoint-statuses">{{range .Statuses}}<span class="status-{{.Class}}" style="width: {{printf "%.2f" .Percent}}%;" title="{{.Class}}: {{.Count}}"></span>{{end}}</div>
+                {{range $i, $s := .Statuses}}{{if $i}}，{{end}}{{$s.Class}} {{$s.Count}}{{end}}
+            </td>
+            <td>{{printf "%.2f" .Average}} / {{printf "%.2f" .P50}} / {{printf "%.2f" .P99}}</td>
+        </tr>
+        {{end}}
+    </table>
+    {{end}}
+</div>`
+
+// 接口统计处理函数，返回HTML片段
+func endpointsHandler(w http.ResponseWriter, r *http.Request) {
+	sid, file, ok := fileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tmpl, err := template.New("endpoints").Funcs(template.FuncMap{
+		"formatSize": func(size int64) string { return formatFileSize(int(size)) },
+	}).Parse(endpointsTemplate)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("解析模板失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// 与请求列表使用相同的过滤条件
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	filter, err := parseFilter(query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("解析过滤条件失败: %v", err), http.StatusBadRequest)
+		return
+	}
+	sortKey := r.URL.Query().Get("sort")
+	if sortKey == "" {
+		sortKey = "count"
+	}
+	indexes, err := filter.apply(file)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("过滤请求失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+	endpoints := buildEndpointStats(file.HAR, indexes, sortKey)
+	entries := make(map[int]apiEntry)
+	for _, endpoint := range endpoints {
+		// 在当前过滤条件的基础上筛选接口，列表中的数量与统计一致
+		options := listOptions{SessionID: sid, FileID: file.ID, Query: strings.TrimSpace(query + " " + endpoint.Filter), Size: defaultPageSize}
+		endpoint.FilterURL = options.url(nil)
+		for _, index := range endpoint.Members() {
+			entries[index] = newAPIEntry(index, &file.HAR.Log.Entries[index])
+		}
+	}
+
+	// 可排序的列
+	type column struct {
+		Key   string
+		Title string
+		URL   string
+	}
+	var columns []column
+	for _, c := range []struct{ key, title string }{
+		{"count", "请求数"}, {"p90", "p90 (ms)"}, {"max", "最大 (ms)"}, {"errors", "错误"}, {"bytes", "响应大小"},
+	} {
+		src := fmt.Sprintf("/endpoints?sid=%s&fid=%s&q=%s&sort=%s", url.QueryEscape(sid), url.QueryEscape(file.ID), url.QueryEscape(query), c.key)
+		columns = append(columns, column{Key: c.key, Title: c.title, URL: src})
+	}
+
+	tmpl.Execute(w, map[string]interface{}{
+		"Total":     len(indexes),
+		"Endpoints": endpoints,
+		"Entries":   entries,
+		"Columns":   columns,
+		"Sort":      sortKey,
+		"Query":     query,
+	})
+}
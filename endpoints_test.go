@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 接口过滤条件中的空格和引号被转义，拆分后与原值一致
+func TestEndpointFilter(t *testing.T) {
+	name := `example.com/a b/"{id}"`
+	got := splitQuery(endpointFilter("GET", name))
+	if want := []string{"method:GET", "endpoint:" + name}; !reflect.DeepEqual(got, want) {
+		t.Errorf("拆分结果为 %q，应为 %q", got, want)
+	}
+}
+
+// 接口统计使用请求列表的过滤条件
+func TestEndpointsHandlerQuery(t *testing.T) {
+	server, client := newTestServer(t)
+	sid := store.newSession()
+	defer store.closeAll(sid)
+
+	fid, err := uploadTestHAR(client, server.URL, sid, testHAR(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(query string) string {
+		resp, err := client.Get(server.URL + "/endpoints?sid=" + sid + "&fid=" + fid + "&q=" + url.QueryEscape(query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	if body := get(""); !strings.Contains(body, "10 个请求归为 1 个接口") {
+		t.Errorf("未过滤时的统计为 %s", body)
+	}
+	body := get("time:<3")
+	if !strings.Contains(body, "符合过滤条件的 3 个请求归为 1 个接口") {
+		t.Errorf("过滤后的统计为 %s", body)
+	}
+	// 查看接口请求的链接和排序链接保留过滤条件
+	if !strings.Contains(body, "q=time%3A%3C3&#43;method%3AGET&#43;endpoint%3Aexample.com%2Fitems%2F%7Bid%7D") {
+		t.Errorf("接口链接未保留过滤条件: %s", body)
+	}
+	if !strings.Contains(body, "q=time%3A%3C3\\u0026sort=p90") {
+		t.Errorf("排序链接未保留过滤条件: %s", body)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+// 可导出的列
+type exportColumn struct {
+	Key     string
+	Title   string
+	Default bool // 页面上默认勾选
+	Number  bool // xlsx 中按数值写入
+	value   func(entry *Entry) string
+}
+
+var exportColumns = []exportColumn{
+	{"method", "方法", true, false, func(e *Entry) string { return e.Request.Method }},
+	{"url", "URL", true, false, func(e *Entry) string { return e.Request.URL }},
+	{"status", "状态码", true, true, func(e *Entry) string { return strconv.Itoa(e.Response.Status) }},
+	{"time", "耗时(ms)", true, true, func(e *Entry) string { return strconv.FormatFloat(e.Time, 'f', 2, 64) }},
+	{"size", "大小(byte)", true, true, func(e *Entry) string { return strconv.FormatInt(e.Response.Content.Size, 10) }},
+	{"mime", "MIME类型", false, false, func(e *Entry) string { return e.Response.Content.MimeType }},
+	{"started", "开始时间", false, false, func(e *Entry) string { return e.StartedDateTime }},
+	{"serverIP", "服务器IP", false, false, func(e *Entry) string { return e.ServerIPAddress }},
+}
+
+// 导出为其他工具可以导入的文件，包含完整的请求和响应
+type dataExportFormat struct {
+	Key    string
+	Name   string
+	Suffix string
+}
+
+var dataExportFormats = []dataExportFormat{
+	{"har", "HAR 1.2", ".har"},
+	{"postman", "Postman Collection v2.1", ".postman_collection.json"},
+	{"openapi", "OpenAPI 3", ".openapi.json"},
+}
+
+// 查找导出格式，不存在时返回 nil
+func findDataExportFormat(key string) *dataExportFormat {
+	for i := range dataExportFormats {
+		if dataExportFormats[i].Key == key {
+			return &dataExportFormats[i]
+		}
+	}
+	return nil
+}
+
+// 导出的文件名，如 capture.chlsj 导出为 capture.postman_collection.json
+func (f *dataExportFormat) fileName(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name)) + f.Suffix
+}
+
+// 按指定格式导出条目，indexes 为过滤和排序后的条目序号
+func writeDataExport(w io.Writer, file *harFile, indexes []int, format string) error {
+	if format == "har" {
+		// 只保留导出的条目引用的页面
+		harLog := file.HAR.Log
+		pagerefs := make(map[string]bool)
+		for _, index := range indexes {
+			pagerefs[file.HAR.Log.Entries[index].Pageref] = true
+		}
+		harLog.Pages = nil
+		for _, page := range file.HAR.Log.Pages {
+			if pagerefs[page.ID] {
+				harLog.Pages = append(harLog.Pages, page)
+			}
+		}
+		return writeHAR(w, harLog, len(indexes), func(i int) (*Entry, error) {
+			entry, err := file.Entry(indexes[i])
+			return &entry, err
+		})
+	}
+
+	switch format {
+	case "postman":
+		return writePostmanCollection(w, file, indexes)
+	case "openapi":
+		doc, err := buildOpenAPI(file.Name, len(indexes), func(i int) (*Entry, error) {
+			entry, err := file.Entry(indexes[i])
+			return &entry, err
+		})
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, doc)
+	}
+	return fmt.Errorf("不支持的导出格式: %s", format)
+}
+
+// 导出的内容已经开始输出后出错，无法再返回错误页面，记录错误并中断连接，让客户端知道下载不完整
+func abortExport(name string, err error) {
+	fmt.Printf("导出 %s 失败: %v\n", name, err)
+	panic(http.ErrAbortHandler)
+}
+
+// 根据请求参数选择导出的列，column 为内置列，requestHeaders 和 responseHeaders 为逗号分隔的头部名称
+func selectExportColumns(r *http.Request) []exportColumn {
+	query := r.URL.Query()
+	selected := make(map[string]bool)
+	for _, key := range query["column"] {
+		selected[key] = true
+	}
+
+	var columns []exportColumn
+	for _, column := range exportColumns {
+		if selected[column.Key] || (len(selected) == 0 && column.Default) {
+			columns = append(columns, column)
+		}
+	}
+	for _, name := range strings.Split(query.Get("requestHeaders"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			columns = append(columns, exportColumn{Title: "请求头 " + name, value: func(e *Entry) string {
+				return headerValue(e.Request.Headers, name)
+			}})
+		}
+	}
+	for _, name := range strings.Split(query.Get("responseHeaders"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			columns = append(columns, exportColumn{Title: "响应头 " + name, value: func(e *Entry) string {
+				return headerValue(e.Response.Headers, name)
+			}})
+		}
+	}
+	return columns
+}
+
+// CSV编码选择框
+var csvEncodingTemplate = `<select name="encoding">
+    <option value="gbk">GBK</option>
+    <option value="utf8bom">UTF-8 BOM</option>
+    <option value="utf8">UTF-8</option>
+</select>`
+
+// 按指定编码转换CSV内容，返回转换后的内容和字符集名称
+// 支持 utf8、utf8bom 和 gbk（默认），GBK 无法表示的字符替换为 ?
+func encodeCSV(data []byte, name string) ([]byte, string) {
+	switch name {
+	case "utf8":
+		return data, "UTF-8"
+	case "utf8bom":
+		return append([]byte("\xEF\xBB\xBF"), data...), "UTF-8"
+	default:
+		encoder := encoding.ReplaceUnsupported(simplifiedchinese.GBK.NewEncoder())
+		gbkBuf, _, _ := transform.Bytes(encoder, data)
+		return gbkBuf, "GBK"
+	}
+}
+
+// 导出条目处理函数，使用当前的过滤条件和排序
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	sid, file, ok := fileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	options := parseListOptions(r, sid, file.ID)
+	filter, err := parseFilter(options.Query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("解析过滤条件失败: %v", err), http.StatusBadRequest)
+		return
+	}
+	indexes, err := filter.apply(file)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("过滤请求失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sortIndexes(file.HAR.Log.Entries, indexes, options.Sort, options.Order)
+
+	if format := findDataExportFormat(r.URL.Query().Get("format")); format != nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(format.fileName(file.Name))))
+		if err := writeDataExport(w, file, indexes, format.Key); err != nil {
+			abortExport(file.Name, err)
+		}
+		return
+	}
+
+	columns := selectExportColumns(r)
+	if len(columns) == 0 {
+		http.Error(w, "请至少选择一列", http.StatusBadRequest)
+		return
+	}
+
+	// 生成表格内容，第一行为标题
+	rows := make([][]xlsxCell, 0, len(indexes)+1)
+	header := make([]xlsxCell, len(columns))
+	for i, column := range columns {
+		header[i] = xlsxCell{Value: column.Title}
+	}
+	rows = append(rows, header)
+	for _, index := range indexes {
+		entry := &file.HAR.Log.Entries[index]
+		row := make([]xlsxCell, len(columns))
+		for i, column := range columns {
+			row[i] = xlsxCell{Value: column.value(entry), Number: column.Number}
+		}
+		rows = append(rows, row)
+	}
+
+	if r.URL.Query().Get("format") == "xlsx" {
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment; filename=entries.xlsx")
+		if err := writeXLSX(w, "entries", rows); err != nil {
+			abortExport(file.Name, err)
+		}
+		return
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			record[i] = cell.Value
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+
+	csvContent, charset := encodeCSV(buf.Bytes(), r.URL.Query().Get("encoding"))
+	w.Header().Set("Content-Type", "text/csv; charset="+charset)
+	w.Header().Set("Content-Disposition", "attachment; filename=entries.csv")
+	w.Write(csvContent)
+}
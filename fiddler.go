@@ -0,0 +1,250 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fiddler 会话元数据（NN_m.xml）
+type fiddlerMeta struct {
+	Timers struct {
+		ClientConnected     string `xml:"ClientConnected,attr"`
+		ClientBeginRequest  string `xml:"ClientBeginRequest,attr"`
+		ClientDoneRequest   string `xml:"ClientDoneRequest,attr"`
+		DNSTime             string `xml:"DNSTime,attr"`
+		TCPConnectTime      string `xml:"TCPConnectTime,attr"`
+		HTTPSHandshakeTime  string `xml:"HTTPSHandshakeTime,attr"`
+		ServerGotRequest    string `xml:"ServerGotRequest,attr"`
+		ServerBeginResponse string `xml:"ServerBeginResponse,attr"`
+		ServerDoneResponse  string `xml:"ServerDoneResponse,attr"`
+		ClientDoneResponse  string `xml:"ClientDoneResponse,attr"`
+	} `xml:"SessionTimers"`
+	Flags []struct {
+		Name  string `xml:"N,attr"`
+		Value string `xml:"V,attr"`
+	} `xml:"SessionFlags>SessionFlag"`
+}
+
+// 获取会话标记
+func (m *fiddlerMeta) flag(name string) string {
+	for _, f := range m.Flags {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// 解密的 HTTPS 会话带有 https-Client-SNIHostname 等以 https- 开头的标记
+func (m *fiddlerMeta) https() bool {
+	for _, f := range m.Flags {
+		if strings.HasPrefix(strings.ToLower(f.Name), "https-") {
+			return true
+		}
+	}
+	return false
+}
+
+// SAZ 文件是 zip 压缩包
+func sniffZip(head []byte) bool {
+	return bytes.HasPrefix(head, []byte("PK\x03\x04"))
+}
+
+// zip 需要随机访问，先写入临时文件再读取，返回的 cleanup 用于删除临时文件
+func readZip(r io.Reader) (*zip.Reader, func(), error) {
+	temp, err := os.CreateTemp("", "harviewer-*.zip")
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	cleanup := func() {
+		temp.Close()
+		os.Remove(temp.Name())
+	}
+	size, err := io.Copy(temp, r)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	archive, err := zip.NewReader(temp, size)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return archive, cleanup, nil
+}
+
+// 读取压缩包中的文件
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// 解析 Fiddler 的时间，未记录时为零值（0001-01-01）
+func parseFiddlerTime(value string) time.Time {
+	t, ok := parseHARTime(value)
+	if !ok || t.Year() <= 1 {
+		return time.Time{}
+	}
+	return t
+}
+
+// 转换 Fiddler 的 SAZ 文件，按会话编号逐个读取
+func convertSAZ(r io.Reader, emit func(*Entry) error) error {
+	archive, cleanup, err := readZip(r)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// 按会话编号分组，文件名形如 raw/01_c.txt、raw/01_s.txt、raw/01_m.xml
+	type sazSession struct {
+		request, response, meta *zip.File
+	}
+	sessions := make(map[int]*sazSession)
+	for _, f := range archive.File {
+		name := path.Base(f.Name)
+		id, kind, ok := strings.Cut(name, "_")
+		if !ok || !strings.HasPrefix(f.Name, "raw/") {
+			continue
+		}
+		number, err := strconv.Atoi(id)
+		if err != nil {
+			continue
+		}
+		session := sessions[number]
+		if session == nil {
+			session = &sazSession{}
+			sessions[number] = session
+		}
+		switch kind {
+		case "c.txt":
+			session.request = f
+		case "s.txt":
+			session.response = f
+		case "m.xml":
+			session.meta = f
+		}
+	}
+
+	numbers := make([]int, 0, len(sessions))
+	for number := range sessions {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	for _, number := range numbers {
+		session := sessions[number]
+		if session.request == nil {
+			continue
+		}
+		var data [3][]byte
+		for i, f := range []*zip.File{session.request, session.response, session.meta} {
+			if f == nil {
+				continue
+			}
+			if data[i], err = readZipFile(f); err != nil {
+				return fmt.Errorf("会话 %d: %v", number, err)
+			}
+		}
+		if len(data[0]) == 0 {
+			continue
+		}
+		entry, ok, err := sazEntry(data[0], data[1], data[2])
+		if err != nil {
+			return fmt.Errorf("会话 %d: %v", number, err)
+		}
+		if !ok {
+			continue
+		}
+		if err := emit(&entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 转换单个会话，CONNECT 隧道返回 false
+func sazEntry(rawRequest, rawResponse, rawMeta []byte) (Entry, bool, error) {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(rawRequest)))
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("解析请求失败: %v", err)
+	}
+	if req.Method == "CONNECT" {
+		return Entry{}, false, nil
+	}
+	reqBody, _ := io.ReadAll(req.Body)
+
+	var meta fiddlerMeta
+	if len(rawMeta) > 0 {
+		if err := xml.Unmarshal(rawMeta, &meta); err != nil {
+			return Entry{}, false, fmt.Errorf("解析会话信息失败: %v", err)
+		}
+	}
+	// 解密的 HTTPS 请求使用相对地址
+	if !req.URL.IsAbs() && (strings.HasSuffix(req.Host, ":443") || meta.https()) {
+		req.TLS = &tls.ConnectionState{}
+		req.Host = strings.TrimSuffix(req.Host, ":443")
+	}
+
+	var resp *http.Response
+	var respBody []byte
+	if len(rawResponse) > 0 {
+		resp, err = http.ReadResponse(bufio.NewReader(bytes.NewReader(rawResponse)), req)
+		if err != nil {
+			return Entry{}, false, fmt.Errorf("解析响应失败: %v", err)
+		}
+		respBody, _ = io.ReadAll(resp.Body)
+	}
+
+	timers := meta.Timers
+	started := parseFiddlerTime(timers.ClientBeginRequest)
+	entry := entryFromHTTP(req, reqBody, resp, respBody, started)
+	entry.ServerIPAddress = meta.flag("x-hostip")
+	if port := meta.flag("x-clientport"); port != "" {
+		entry.Connection = port
+	}
+
+	sent := parseFiddlerTime(timers.ServerGotRequest)
+	firstByte := parseFiddlerTime(timers.ServerBeginResponse)
+	done := parseFiddlerTime(timers.ServerDoneResponse)
+	entry.Timings.Send = max(millisBetween(parseFiddlerTime(timers.ClientDoneRequest), sent), 0)
+	entry.Timings.Wait = max(millisBetween(sent, firstByte), 0)
+	entry.Timings.Receive = max(millisBetween(firstByte, done), 0)
+	for _, timing := range []struct {
+		value  string
+		target *float64
+	}{
+		{timers.DNSTime, &entry.Timings.DNS},
+		{timers.TCPConnectTime, &entry.Timings.Connect},
+		{timers.HTTPSHandshakeTime, &entry.Timings.SSL},
+	} {
+		if ms, err := strconv.ParseFloat(timing.value, 64); err == nil && ms > 0 {
+			*timing.target = ms
+		}
+	}
+	// HAR 中 connect 包含 ssl 阶段，Fiddler 分别记录两者
+	if entry.Timings.SSL > 0 {
+		entry.Timings.Connect = max(entry.Timings.Connect, 0) + entry.Timings.SSL
+	}
+	entry.Time = millisBetween(started, parseFiddlerTime(timers.ClientDoneResponse))
+	if entry.Time < 0 {
+		entry.Time = entry.Timings.Send + entry.Timings.Wait + entry.Timings.Receive
+	}
+	return entry, true, nil
+}
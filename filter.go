@@ -0,0 +1,455 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 过滤语法说明，显示在页面上
+const filterHelp = `多个条件用空格分隔，全部满足才匹配，条件前加 - 表示取反：
+method:GET,POST   状态 status:404 / status:4xx / status:>=400 / status:failed
+domain:example.com（包含子域名）  mime:json   page:page_1（页面ID，多个用逗号分隔）
+time:>100 / time:100-500 / time:<2s   size:>10k / size:1k-1m
+url:关键字  header:关键字  body:关键字  /正则表达式/  url:/正则/
+param:名称  param:名称=值  param:=值（查询参数和表单参数，值支持关键字或 /正则/）
+endpoint:api.example.com/users/{id}（接口模板，路径中的ID替换为 {id}，可省略域名）
+不带前缀的关键字会在URL、请求头、响应头、请求体和响应体中搜索
+包含空格时加双引号，如 "url:a b"，\" 和 \\ 表示引号和反斜杠，\/文本\/ 按字面匹配 /文本/`
+
+// 过滤时的候选条目，完整条目（含请求体和响应体）只在需要时读取
+type filterCandidate struct {
+	file   *harFile
+	index  int
+	entry  *Entry
+	loaded bool
+	bodies []string
+	err    error // 从临时文件读取请求体或响应体失败
+}
+
+// 获取请求体和响应体文本，响应体为base64时同时搜索解码后的内容
+func (c *filterCandidate) bodyTexts() []string {
+	if c.loaded {
+		return c.bodies
+	}
+	c.loaded = true
+
+	full, err := c.file.Entry(c.index)
+	if err != nil {
+		c.err = err
+		return nil
+	}
+	if full.Request.PostData != nil && full.Request.PostData.Text != "" {
+		c.bodies = append(c.bodies, full.Request.PostData.Text)
+	}
+	content := full.Response.Content
+	if content.Text != "" {
+		c.bodies = append(c.bodies, content.Text)
+		if strings.EqualFold(content.Encoding, "base64") {
+			if data, _, err := decodeContent(content, full.Response.Headers); err == nil && isPrintableText(data) {
+				c.bodies = append(c.bodies, string(data))
+			}
+		}
+	}
+	return c.bodies
+}
+
+// 获取所有请求头和响应头，格式为 "名称: 值"
+func (c *filterCandidate) headerTexts() []string {
+	var texts []string
+	for _, header := range c.entry.Request.Headers {
+		texts = append(texts, header.Name+": "+header.Value)
+	}
+	for _, header := range c.entry.Response.Headers {
+		texts = append(texts, header.Name+": "+header.Value)
+	}
+	return texts
+}
+
+// 获取查询参数和请求体中的表单参数（已解码）
+func (c *filterCandidate) params() []formField {
+	params := requestQueryParams(&c.entry.Request)
+	if postData := c.entry.Request.PostData; postData != nil {
+		for _, param := range postData.Params {
+			params = append(params, formField{Name: param.Name, Value: param.Value})
+		}
+	}
+	return params
+}
+
+// 文本匹配器，支持不区分大小写的子串和正则表达式
+type textMatcher struct {
+	text string
+	re   *regexp.Regexp
+}
+
+func newTextMatcher(value string) (textMatcher, error) {
+	if len(value) >= 2 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+		re, err := regexp.Compile(value[1 : len(value)-1])
+		if err != nil {
+			return textMatcher{}, fmt.Errorf("正则表达式错误: %v", err)
+		}
+		return textMatcher{re: re}, nil
+	}
+	// 开头和结尾的 \/ 表示字面的 /，用于匹配形如 /.../ 的文本
+	if len(value) >= 4 && strings.HasPrefix(value, `\/`) && strings.HasSuffix(value, `\/`) {
+		value = "/" + value[2:len(value)-2] + "/"
+	}
+	return textMatcher{text: strings.ToLower(value)}, nil
+}
+
+// 转义文本值，使形如 /.../ 的值按字面匹配而不是作为正则
+func literalTextValue(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+		return `\/` + value[1:len(value)-1] + `\/`
+	}
+	return value
+}
+
+func (m textMatcher) match(texts ...string) bool {
+	for _, text := range texts {
+		if m.re != nil {
+			if m.re.MatchString(text) {
+				return true
+			}
+		} else if strings.Contains(strings.ToLower(text), m.text) {
+			return true
+		}
+	}
+	return false
+}
+
+// 数值范围，边界为闭区间
+type numberRange struct {
+	min float64
+	max float64
+}
+
+func (r numberRange) contains(value float64) bool {
+	return value >= r.min && value <= r.max
+}
+
+// 解析数值范围，支持 >n、>=n、<n、<=n、n-m 和 n
+func parseRange(value string, parseNumber func(string) (float64, error)) (numberRange, error) {
+	r := numberRange{min: math.Inf(-1), max: math.Inf(1)}
+	var err error
+	switch {
+	case strings.HasPrefix(value, ">="):
+		r.min, err = parseNumber(value[2:])
+	case strings.HasPrefix(value, "<="):
+		r.max, err = parseNumber(value[2:])
+	case strings.HasPrefix(value, ">"):
+		r.min, err = parseNumber(value[1:])
+		r.min = math.Nextafter(r.min, math.Inf(1))
+	case strings.HasPrefix(value, "<"):
+		r.max, err = parseNumber(value[1:])
+		r.max = math.Nextafter(r.max, math.Inf(-1))
+	case strings.Contains(value[1:], "-"):
+		i := strings.Index(value[1:], "-") + 1
+		if r.min, err = parseNumber(value[:i]); err == nil {
+			r.max, err = parseNumber(value[i+1:])
+		}
+	default:
+		r.min, err = parseNumber(strings.TrimPrefix(value, "="))
+		r.max = r.min
+	}
+	if err != nil {
+		return r, fmt.Errorf("无效的范围 %q", value)
+	}
+	return r, nil
+}
+
+// 解析带单位的大小，支持 b、k、kb、m、mb、g、gb
+func parseSize(value string) (float64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	units := []struct {
+		suffix string
+		scale  float64
+	}{
+		{"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10},
+		{"g", 1 << 30}, {"m", 1 << 20}, {"k", 1 << 10}, {"b", 1},
+	}
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			number, err := strconv.ParseFloat(strings.TrimSuffix(value, unit.suffix), 64)
+			return number * unit.scale, err
+		}
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// 解析时长，默认单位毫秒，支持 ms 和 s
+func parseDuration(value string) (float64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if strings.HasSuffix(value, "ms") {
+		return strconv.ParseFloat(strings.TrimSuffix(value, "ms"), 64)
+	}
+	if strings.HasSuffix(value, "s") {
+		number, err := strconv.ParseFloat(strings.TrimSuffix(value, "s"), 64)
+		return number * 1000, err
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// 支持的过滤字段
+var filterKeys = map[string]bool{
+	"method": true, "status": true, "domain": true, "mime": true,
+	"time": true, "size": true, "url": true, "header": true, "body": true,
+	"page": true, "param": true, "endpoint": true,
+}
+
+// 单个过滤条件
+type filterTerm struct {
+	negate bool
+	match  func(c *filterCandidate) bool
+}
+
+// 条目过滤器
+type entryFilter struct {
+	terms []filterTerm
+}
+
+// 将查询字符串拆分为条件，支持用双引号包含空格，\" 和 \\ 表示字面的引号和反斜杠
+func splitQuery(query string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuote := false
+	escaped := false
+	for _, r := range query {
+		switch {
+		case escaped:
+			if r != '"' && r != '\\' {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			inQuote = !inQuote
+		case (r == ' ' || r == '\t') && !inQuote:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if escaped {
+		current.WriteRune('\\')
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// 生成查询中的单个条件，转义引号和反斜杠，包含空格时加引号，与 splitQuery 对应
+func quoteFilterTerm(term string) string {
+	term = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(term)
+	if strings.ContainsAny(term, " \t") {
+		term = `"` + term + `"`
+	}
+	return term
+}
+
+// 解析过滤查询
+func parseFilter(query string) (*entryFilter, error) {
+	filter := &entryFilter{}
+	for _, token := range splitQuery(query) {
+		term, err := parseFilterTerm(token)
+		if err != nil {
+			return nil, err
+		}
+		filter.terms = append(filter.terms, term)
+	}
+	return filter, nil
+}
+
+// 解析单个过滤条件
+func parseFilterTerm(token string) (filterTerm, error) {
+	term := filterTerm{}
+	if strings.HasPrefix(token, "-") && len(token) > 1 {
+		term.negate = true
+		token = token[1:]
+	}
+
+	// 不是已知字段时（如 https://...）整体作为关键字搜索
+	key, value, found := strings.Cut(token, ":")
+	key = strings.ToLower(key)
+	if !found || !filterKeys[key] || strings.HasPrefix(token, "/") {
+		key, value = "", token
+	}
+	if value == "" {
+		return term, fmt.Errorf("条件 %q 缺少值", token)
+	}
+	match, err := newFilterTerm(key, value)
+	term.match = match.match
+	return term, err
+}
+
+// 根据字段和值生成条件，key 为空时在URL、头部和请求体/响应体中搜索
+func newFilterTerm(key, value string) (filterTerm, error) {
+	term := filterTerm{}
+	switch key {
+	case "method":
+		methods := strings.Split(strings.ToUpper(value), ",")
+		term.match = func(c *filterCandidate) bool {
+			for _, method := range methods {
+				if c.entry.Request.Method == method {
+					return true
+				}
+			}
+			return false
+		}
+	case "status":
+		value = strings.ToLower(value)
+		if value == "failed" || (len(value) == 3 && strings.HasSuffix(value, "xx")) {
+			term.match = func(c *filterCandidate) bool {
+				return statusClass(c.entry.Response.Status) == value
+			}
+			break
+		}
+		r, err := parseRange(value, func(s string) (float64, error) { return strconv.ParseFloat(s, 64) })
+		if err != nil {
+			return term, err
+		}
+		term.match = func(c *filterCandidate) bool {
+			return r.contains(float64(c.entry.Response.Status))
+		}
+	case "domain":
+		domain := strings.ToLower(value)
+		term.match = func(c *filterCandidate) bool {
+			host := strings.ToLower(extractDomain(c.entry.Request.URL))
+			if i := strings.LastIndex(host, ":"); i >= 0 && !strings.HasSuffix(host, "]") {
+				host = host[:i]
+			}
+			return host == domain || strings.HasSuffix(host, "."+domain)
+		}
+	case "endpoint":
+		// 域名不区分大小写，以 / 开头时只比较路径模板
+		domain, path := "", value
+		if i := strings.Index(value, "/"); i > 0 {
+			domain, path = value[:i], value[i:]
+		}
+		term.match = func(c *filterCandidate) bool {
+			host, template, ok := endpointTemplate(c.entry.Request.URL)
+			if !ok || template != path {
+				return false
+			}
+			return domain == "" || strings.EqualFold(host, domain)
+		}
+	case "mime":
+		mime := strings.ToLower(value)
+		term.match = func(c *filterCandidate) bool {
+			return strings.Contains(strings.ToLower(c.entry.Response.Content.MimeType), mime)
+		}
+	case "page":
+		pages := strings.Split(value, ",")
+		term.match = func(c *filterCandidate) bool {
+			for _, page := range pages {
+				if c.entry.Pageref == page {
+					return true
+				}
+			}
+			return false
+		}
+	case "param":
+		// 名称不区分大小写完全匹配，省略"="时只比较名称，名称为空时只比较值
+		name, text, hasValue := strings.Cut(value, "=")
+		matcher, err := newTextMatcher(text)
+		if err != nil {
+			return term, err
+		}
+		term.match = func(c *filterCandidate) bool {
+			for _, param := range c.params() {
+				if name != "" && !strings.EqualFold(param.Name, name) {
+					continue
+				}
+				if !hasValue || matcher.match(param.Value) {
+					return true
+				}
+			}
+			return false
+		}
+	case "time":
+		r, err := parseRange(value, parseDuration)
+		if err != nil {
+			return term, err
+		}
+		term.match = func(c *filterCandidate) bool {
+			return r.contains(c.entry.Time)
+		}
+	case "size":
+		r, err := parseRange(value, parseSize)
+		if err != nil {
+			return term, err
+		}
+		term.match = func(c *filterCandidate) bool {
+			return r.contains(float64(c.entry.Response.Content.Size))
+		}
+	case "url", "header", "body", "":
+		matcher, err := newTextMatcher(value)
+		if err != nil {
+			return term, err
+		}
+		switch key {
+		case "url":
+			term.match = func(c *filterCandidate) bool {
+				return matcher.match(c.entry.Request.URL)
+			}
+		case "header":
+			term.match = func(c *filterCandidate) bool {
+				return matcher.match(c.headerTexts()...)
+			}
+		case "body":
+			term.match = func(c *filterCandidate) bool {
+				return matcher.match(c.bodyTexts()...)
+			}
+		default:
+			term.match = func(c *filterCandidate) bool {
+				return matcher.match(c.entry.Request.URL) ||
+					matcher.match(c.headerTexts()...) ||
+					matcher.match(c.bodyTexts()...)
+			}
+		}
+	}
+	return term, nil
+}
+
+// 判断是否为空过滤器
+func (f *entryFilter) empty() bool {
+	return f == nil || len(f.terms) == 0
+}
+
+// 判断条目是否满足所有条件，读取请求体或响应体失败时结果不可信，返回错误
+func (f *entryFilter) match(file *harFile, index int) (bool, error) {
+	if f.empty() {
+		return true, nil
+	}
+	candidate := &filterCandidate{file: file, index: index, entry: &file.HAR.Log.Entries[index]}
+	for _, term := range f.terms {
+		if term.match(candidate) == term.negate {
+			return false, candidate.err
+		}
+	}
+	return true, candidate.err
+}
+
+// 获取所有满足条件的条目序号，出错时返回已匹配的部分和错误
+func (f *entryFilter) apply(file *harFile) ([]int, error) {
+	indexes := make([]int, 0, len(file.HAR.Log.Entries))
+	for i := range file.HAR.Log.Entries {
+		ok, err := f.match(file, i)
+		if err != nil {
+			return indexes, fmt.Errorf("读取第 %d 个请求的内容失败: %v", i, err)
+		}
+		if ok {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
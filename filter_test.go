@@ -0,0 +1,161 @@
+package main
+
+import (
+	"io"
+	"math"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"  method:GET \t status:200 ", []string{"method:GET", "status:200"}},
+		{`body:"hello world" url:api`, []string{"body:hello world", "url:api"}},
+		{`"header:x-id: 1"`, []string{"header:x-id: 1"}},
+		{`body:\"quoted\"`, []string{`body:"quoted"`}},
+		{`"body:a \"b c\""`, []string{`body:a "b c"`}},
+		{`url:a\\b`, []string{`url:a\b`}},
+		{`url:a\d+`, []string{`url:a\d+`}},
+		{`url:end\`, []string{`url:end\`}},
+		{`"unclosed quote`, []string{"unclosed quote"}},
+	}
+	for _, test := range tests {
+		if got := splitQuery(test.query); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitQuery(%q) = %q，应为 %q", test.query, got, test.want)
+		}
+	}
+}
+
+// quoteFilterTerm 生成的条件经 splitQuery 拆分后与原值一致
+func TestQuoteFilterTerm(t *testing.T) {
+	tests := []struct {
+		term string
+		want string
+	}{
+		{"method:GET", "method:GET"},
+		{"body:hello world", `"body:hello world"`},
+		{`body:say "hi"`, `"body:say \"hi\""`},
+		{`url:a\b`, `url:a\\b`},
+		{`url:end\`, `url:end\\`},
+		{"header:a\tb", "\"header:a\tb\""},
+	}
+	for _, test := range tests {
+		got := quoteFilterTerm(test.term)
+		if got != test.want {
+			t.Errorf("quoteFilterTerm(%q) = %q，应为 %q", test.term, got, test.want)
+		}
+		if tokens := splitQuery(got + " status:200"); !reflect.DeepEqual(tokens, []string{test.term, "status:200"}) {
+			t.Errorf("%q 拆分后为 %q", got, tokens)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		value    string
+		parse    func(string) (float64, error)
+		min, max float64
+		err      bool
+	}{
+		{"200", parseDuration, 200, 200, false},
+		{"=1.5s", parseDuration, 1500, 1500, false},
+		{">=500ms", parseDuration, 500, inf, false},
+		{"<=2s", parseDuration, -inf, 2000, false},
+		{"100-300", parseDuration, 100, 300, false},
+		{"1kb-2mb", parseSize, 1024, 2 << 20, false},
+		{">1g", parseSize, math.Nextafter(1<<30, inf), inf, false},
+		{"<10b", parseSize, -inf, math.Nextafter(10, -inf), false},
+		{"-5", parseDuration, -5, -5, false},
+		{">abc", parseDuration, 0, 0, true},
+		{"1-x", parseSize, 0, 0, true},
+		{"10xb", parseSize, 0, 0, true},
+	}
+	for _, test := range tests {
+		r, err := parseRange(test.value, test.parse)
+		if test.err {
+			if err == nil {
+				t.Errorf("parseRange(%q) 未返回错误", test.value)
+			}
+			continue
+		}
+		if err != nil || r.min != test.min || r.max != test.max {
+			t.Errorf("parseRange(%q) = %v %v %v，应为 %v %v", test.value, r.min, r.max, err, test.min, test.max)
+		}
+	}
+	if r, _ := parseRange(">1s", parseDuration); r.contains(1000) || !r.contains(1000.5) {
+		t.Error(">1s 的边界错误")
+	}
+}
+
+// 形如 /.../ 的值作为正则，literalTextValue 转义后按字面匹配
+func TestTextMatcher(t *testing.T) {
+	tests := []struct {
+		value string
+		text  string
+		want  bool
+	}{
+		{"Hello", "say hello!", true},
+		{"/^say h.llo/", "say hello!", true},
+		{"/^hello/", "say hello!", false},
+		{"/api/", "GET /api/users", true},
+		{"/api/", "GET /v1api", true},
+		{literalTextValue("/api/"), "GET /api/users", true},
+		{literalTextValue("/api/"), "GET /v1api", false},
+		{literalTextValue("/API/"), "get /api/users", true},
+		{literalTextValue("plain"), "plain text", true},
+		{`\/x`, `\/x`, true},
+	}
+	for _, test := range tests {
+		matcher, err := newTextMatcher(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := matcher.match(test.text); got != test.want {
+			t.Errorf("%q 匹配 %q 结果为 %v", test.value, test.text, got)
+		}
+	}
+	if _, err := newTextMatcher("/(/"); err == nil {
+		t.Error("错误的正则表达式未报错")
+	}
+}
+
+// 列表页面显示过滤时读取请求体的错误
+func TestFilterReadError(t *testing.T) {
+	server, client := newTestServer(t)
+	sid := store.newSession()
+	defer store.closeAll(sid)
+
+	fid, err := uploadTestHAR(client, server.URL, sid, testHAR(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := store.file(sid, fid)
+	filter, err := parseFilter("body:body-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexes, err := filter.apply(file); err != nil || !reflect.DeepEqual(indexes, []int{1}) {
+		t.Fatalf("过滤结果为 %v %v", indexes, err)
+	}
+
+	file.spill.Close()
+	if _, err := filter.apply(file); err == nil {
+		t.Error("读取请求体失败时未返回错误")
+	}
+	resp, err := client.Get(server.URL + "/?sid=" + sid + "&fid=" + fid + "&q=" + url.QueryEscape("body:body-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "读取第 0 个请求的内容失败") {
+		t.Error("列表页面未显示读取错误")
+	}
+}
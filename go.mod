@@ -4,7 +4,10 @@ go 1.25.4
 
 require (
 	fyne.io/fyne/v2 v2.7.1
+	github.com/andybalholm/brotli v1.2.5
 	github.com/fyne-io/image v0.1.1
+	github.com/klauspost/compress v1.20.1
+	golang.org/x/net v0.35.0
 	golang.org/x/text v0.22.0
 )
 
@@ -36,7 +39,6 @@ require (
 	github.com/stretchr/testify v1.11.1 // indirect
 	github.com/yuin/goldmark v1.7.8 // indirect
 	golang.org/x/image v0.24.0 // indirect
-	golang.org/x/net v0.35.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
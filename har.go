@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+)
+
+// HAR 1.2 对象模型，字段定义参考 http://www.softwareishard.com/blog/har-12-spec/
+// 各对象中以"_"开头的自定义字段保存在 Custom 中，序列化时原样写回
+
+// 定义HAR文件结构体
+type HAR struct {
+	Log Log `json:"log"`
+}
+
+type Log struct {
+	Version string       `json:"version"`
+	Creator Creator      `json:"creator"`
+	Browser *Creator     `json:"browser,omitempty"`
+	Pages   []Page       `json:"pages,omitempty"`
+	Entries []Entry      `json:"entries"`
+	Comment string       `json:"comment,omitempty"`
+	Custom  CustomFields `json:"-"`
+}
+
+// Creator 同时用于 creator 和 browser 对象
+type Creator struct {
+	Name    string       `json:"name"`
+	Version string       `json:"version"`
+	Comment string       `json:"comment,omitempty"`
+	Custom  CustomFields `json:"-"`
+}
+
+type Page struct {
+	StartTime   string       `json:"startedDateTime"`
+	ID          string       `json:"id"`
+	Title       string       `json:"title"`
+	PageTimings PageTimings  `json:"pageTimings"`
+	Comment     string       `json:"comment,omitempty"`
+	Custom      CustomFields `json:"-"`
+}
+
+// 页面加载时间，-1 表示不可用
+type PageTimings struct {
+	OnContentLoad float64      `json:"onContentLoad"`
+	OnLoad        float64      `json:"onLoad"`
+	Comment       string       `json:"comment,omitempty"`
+	Custom        CustomFields `json:"-"`
+}
+
+type Entry struct {
+	Pageref         string       `json:"pageref,omitempty"`
+	StartedDateTime string       `json:"startedDateTime"`
+	Time            float64      `json:"time"`
+	Request         Request      `json:"request"`
+	Response        Response     `json:"response"`
+	Cache           Cache        `json:"cache"`
+	Timings         Timings      `json:"timings"`
+	ServerIPAddress string       `json:"serverIPAddress,omitempty"`
+	Connection      string       `json:"connection,omitempty"`
+	Comment         string       `json:"comment,omitempty"`
+	Custom          CustomFields `json:"-"`
+}
+
+type Request struct {
+	Method      string        `json:"method"`
+	URL         string        `json:"url"`
+	HTTPVersion string        `json:"httpVersion"`
+	Cookies     []Cookie      `json:"cookies"`
+	Headers     []Header      `json:"headers"`
+	QueryString []QueryString `json:"queryString"`
+	PostData    *PostData     `json:"postData,omitempty"`
+	HeadersSize int64         `json:"headersSize"`
+	BodySize    int64         `json:"bodySize"`
+	Comment     string        `json:"comment,omitempty"`
+	Custom      CustomFields  `json:"-"`
+}
+
+type Response struct {
+	Status      int          `json:"status"`
+	StatusText  string       `json:"statusText"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []Cookie     `json:"cookies"`
+	Headers     []Header     `json:"headers"`
+	Content     Content      `json:"content"`
+	RedirectURL string       `json:"redirectURL"`
+	HeadersSize int64        `json:"headersSize"`
+	BodySize    int64        `json:"bodySize"`
+	Comment     string       `json:"comment,omitempty"`
+	Custom      CustomFields `json:"-"`
+}
+
+// SameSite 不属于 HAR 1.2 规范，但 Chrome 导出的文件会携带
+type Cookie struct {
+	Name     string       `json:"name"`
+	Value    string       `json:"value"`
+	Path     string       `json:"path,omitempty"`
+	Domain   string       `json:"domain,omitempty"`
+	Expires  string       `json:"expires,omitempty"`
+	HTTPOnly bool         `json:"httpOnly,omitempty"`
+	Secure   bool         `json:"secure,omitempty"`
+	SameSite string       `json:"sameSite,omitempty"`
+	Comment  string       `json:"comment,omitempty"`
+	Custom   CustomFields `json:"-"`
+}
+
+type Header struct {
+	Name    string       `json:"name"`
+	Value   string       `json:"value"`
+	Comment string       `json:"comment,omitempty"`
+	Custom  CustomFields `json:"-"`
+}
+
+type QueryString struct {
+	Name    string       `json:"name"`
+	Value   string       `json:"value"`
+	Comment string       `json:"comment,omitempty"`
+	Custom  CustomFields `json:"-"`
+}
+
+type PostData struct {
+	MimeType string       `json:"mimeType"`
+	Params   []Param      `json:"params,omitempty"`
+	Text     string       `json:"text"`
+	Comment  string       `json:"comment,omitempty"`
+	Custom   CustomFields `json:"-"`
+}
+
+type Param struct {
+	Name        string       `json:"name"`
+	Value       string       `json:"value,omitempty"`
+	FileName    string       `json:"fileName,omitempty"`
+	ContentType string       `json:"contentType,omitempty"`
+	Comment     string       `json:"comment,omitempty"`
+	Custom      CustomFields `json:"-"`
+}
+
+type Content struct {
+	Size        int64        `json:"size"`
+	Compression int64        `json:"compression,omitempty"`
+	MimeType    string       `json:"mimeType"`
+	Text        string       `json:"text,omitempty"`
+	Encoding    string       `json:"encoding,omitempty"`
+	Comment     string       `json:"comment,omitempty"`
+	Custom      CustomFields `json:"-"`
+}
+
+type Cache struct {
+	BeforeRequest *CacheEntry  `json:"beforeRequest,omitempty"`
+	AfterRequest  *CacheEntry  `json:"afterRequest,omitempty"`
+	Comment       string       `json:"comment,omitempty"`
+	Custom        CustomFields `json:"-"`
+}
+
+type CacheEntry struct {
+	Expires    string       `json:"expires,omitempty"`
+	LastAccess string       `json:"lastAccess"`
+	ETag       string       `json:"eTag"`
+	HitCount   int          `json:"hitCount"`
+	Comment    string       `json:"comment,omitempty"`
+	Custom     CustomFields `json:"-"`
+}
+
+// 请求各阶段耗时（毫秒），可选阶段为 -1 表示不适用
+type Timings struct {
+	Blocked float64      `json:"blocked"`
+	DNS     float64      `json:"dns"`
+	Connect float64      `json:"connect"`
+	Send    float64      `json:"send"`
+	Wait    float64      `json:"wait"`
+	Receive float64      `json:"receive"`
+	SSL     float64      `json:"ssl"`
+	Comment string       `json:"comment,omitempty"`
+	Custom  CustomFields `json:"-"`
+}
+
+// CustomFields 保存以"_"开头的自定义字段的原始JSON
+type CustomFields map[string]json.RawMessage
+
+// 解析对象并提取其中的自定义字段
+func decodeObject(data []byte, v interface{}, custom *CustomFields) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	*custom = nil
+
+	// 没有以"_"开头的键时跳过第二次解析
+	if !bytes.Contains(data, []byte(`"_`)) {
+		return nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for key, value := range raw {
+		if strings.HasPrefix(key, "_") {
+			if *custom == nil {
+				*custom = make(CustomFields)
+			}
+			(*custom)[key] = value
+		}
+	}
+	return nil
+}
+
+// 序列化对象并追加自定义字段
+func encodeObject(v interface{}, custom CustomFields) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(custom) == 0 {
+		return data, err
+	}
+
+	keys := make([]string, 0, len(custom))
+	for key := range custom {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	for i, key := range keys {
+		if i > 0 || len(data) > 2 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(custom[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (l *Log) UnmarshalJSON(data []byte) error {
+	type plain Log
+	return decodeObject(data, (*plain)(l), &l.Custom)
+}
+
+func (l Log) MarshalJSON() ([]byte, error) {
+	type plain Log
+	return encodeObject(plain(l), l.Custom)
+}
+
+func (c *Creator) UnmarshalJSON(data []byte) error {
+	type plain Creator
+	return decodeObject(data, (*plain)(c), &c.Custom)
+}
+
+func (c Creator) MarshalJSON() ([]byte, error) {
+	type plain Creator
+	return encodeObject(plain(c), c.Custom)
+}
+
+func (p *Page) UnmarshalJSON(data []byte) error {
+	type plain Page
+	p.PageTimings = PageTimings{OnContentLoad: -1, OnLoad: -1}
+	return decodeObject(data, (*plain)(p), &p.Custom)
+}
+
+func (p Page) MarshalJSON() ([]byte, error) {
+	type plain Page
+	return encodeObject(plain(p), p.Custom)
+}
+
+func (t *PageTimings) UnmarshalJSON(data []byte) error {
+	type plain PageTimings
+	t.OnContentLoad, t.OnLoad = -1, -1
+	return decodeObject(data, (*plain)(t), &t.Custom)
+}
+
+func (t PageTimings) MarshalJSON() ([]byte, error) {
+	type plain PageTimings
+	return encodeObject(plain(t), t.Custom)
+}
+
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	type plain Entry
+	e.Timings = Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+	return decodeObject(data, (*plain)(e), &e.Custom)
+}
+
+func (e Entry) MarshalJSON() ([]byte, error) {
+	type plain Entry
+	return encodeObject(plain(e), e.Custom)
+}
+
+func (r *Request) UnmarshalJSON(data []byte) error {
+	type plain Request
+	return decodeObject(data, (*plain)(r), &r.Custom)
+}
+
+func (r Request) MarshalJSON() ([]byte, error) {
+	type plain Request
+	return encodeObject(plain(r), r.Custom)
+}
+
+func (r *Response) UnmarshalJSON(data []byte) error {
+	type plain Response
+	return decodeObject(data, (*plain)(r), &r.Custom)
+}
+
+func (r Response) MarshalJSON() ([]byte, error) {
+	type plain Response
+	return encodeObject(plain(r), r.Custom)
+}
+
+func (c *Cookie) UnmarshalJSON(data []byte) error {
+	type plain Cookie
+	return decodeObject(data, (*plain)(c), &c.Custom)
+}
+
+func (c Cookie) MarshalJSON() ([]byte, error) {
+	type plain Cookie
+	return encodeObject(plain(c), c.Custom)
+}
+
+func (h *Header) UnmarshalJSON(data []byte) error {
+	type plain Header
+	return decodeObject(data, (*plain)(h), &h.Custom)
+}
+
+func (h Header) MarshalJSON() ([]byte, error) {
+	type plain Header
+	return encodeObject(plain(h), h.Custom)
+}
+
+func (q *QueryString) UnmarshalJSON(data []byte) error {
+	type plain QueryString
+	return decodeObject(data, (*plain)(q), &q.Custom)
+}
+
+func (q QueryString) MarshalJSON() ([]byte, error) {
+	type plain QueryString
+	return encodeObject(plain(q), q.Custom)
+}
+
+func (p *PostData) UnmarshalJSON(data []byte) error {
+	type plain PostData
+	return decodeObject(data, (*plain)(p), &p.Custom)
+}
+
+func (p PostData) MarshalJSON() ([]byte, error) {
+	type plain PostData
+	return encodeObject(plain(p), p.Custom)
+}
+
+func (p *Param) UnmarshalJSON(data []byte) error {
+	type plain Param
+	return decodeObject(data, (*plain)(p), &p.Custom)
+}
+
+func (p Param) MarshalJSON() ([]byte, error) {
+	type plain Param
+	return encodeObject(plain(p), p.Custom)
+}
+
+func (c *Content) UnmarshalJSON(data []byte) error {
+	type plain Content
+	return decodeObject(data, (*plain)(c), &c.Custom)
+}
+
+func (c Content) MarshalJSON() ([]byte, error) {
+	type plain Content
+	return encodeObject(plain(c), c.Custom)
+}
+
+func (c *Cache) UnmarshalJSON(data []byte) error {
+	type plain Cache
+	return decodeObject(data, (*plain)(c), &c.Custom)
+}
+
+func (c Cache) MarshalJSON() ([]byte, error) {
+	type plain Cache
+	return encodeObject(plain(c), c.Custom)
+}
+
+func (c *CacheEntry) UnmarshalJSON(data []byte) error {
+	type plain CacheEntry
+	return decodeObject(data, (*plain)(c), &c.Custom)
+}
+
+func (c CacheEntry) MarshalJSON() ([]byte, error) {
+	type plain CacheEntry
+	return encodeObject(plain(c), c.Custom)
+}
+
+func (t *Timings) UnmarshalJSON(data []byte) error {
+	type plain Timings
+	t.Blocked, t.DNS, t.Connect, t.SSL = -1, -1, -1, -1
+	return decodeObject(data, (*plain)(t), &t.Custom)
+}
+
+func (t Timings) MarshalJSON() ([]byte, error) {
+	type plain Timings
+	return encodeObject(plain(t), t.Custom)
+}
+
+// 流式写出HAR文件的写入器，log 的其他字段写在 entries 之前，条目逐个追加
+type harWriter struct {
+	w       io.Writer
+	entries int
+}
+
+// 写出 log 中条目以外的字段并开始 entries 数组
+func newHARWriter(w io.Writer, log Log) (*harWriter, error) {
+	type header struct {
+		Version string   `json:"version"`
+		Creator Creator  `json:"creator"`
+		Browser *Creator `json:"browser,omitempty"`
+		Pages   []Page   `json:"pages,omitempty"`
+		Comment string   `json:"comment,omitempty"`
+	}
+	data, err := encodeObject(header{log.Version, log.Creator, log.Browser, log.Pages, log.Comment}, log.Custom)
+	if err != nil {
+		return nil, err
+	}
+	// data 至少包含 version 和 creator，去掉结尾的 } 后接上 entries
+	if _, err := io.WriteString(w, `{"log":`); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data[:len(data)-1]); err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, `,"entries":[`); err != nil {
+		return nil, err
+	}
+	return &harWriter{w: w}, nil
+}
+
+// 追加一个条目
+func (hw *harWriter) write(entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if hw.entries > 0 {
+		if _, err := io.WriteString(hw.w, ","); err != nil {
+			return err
+		}
+	}
+	hw.entries++
+	_, err = hw.w.Write(data)
+	return err
+}
+
+// 结束 entries 数组和文件
+func (hw *harWriter) close() error {
+	_, err := io.WriteString(hw.w, "]}}")
+	return err
+}
+
+// 流式写出HAR文件，条目通过 entry 逐个获取，避免同时将所有请求体和响应体读入内存
+func writeHAR(w io.Writer, log Log, count int, entry func(i int) (*Entry, error)) error {
+	hw, err := newHARWriter(w, log)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		e, err := entry(i)
+		if err != nil {
+			return err
+		}
+		if err := hw.write(e); err != nil {
+			return err
+		}
+	}
+	return hw.close()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// 自定义字段中包含 "entries":null 时也能正确写出条目
+func TestWriteHARCustomFields(t *testing.T) {
+	trap := json.RawMessage(`{"note":"\"entries\":null","entries":null}`)
+	log := Log{
+		Version: "1.2",
+		Creator: Creator{Name: "test", Version: "1", Custom: CustomFields{"_x": trap}},
+		Browser: &Creator{Name: "browser", Version: "1", Custom: CustomFields{"_y": trap}},
+		Pages:   []Page{{ID: "page_1", Title: "t", Custom: CustomFields{"_z": trap}}},
+		Comment: "comment",
+		Custom:  CustomFields{"_log": trap},
+	}
+	entries := []Entry{
+		{StartedDateTime: "2024-01-01T00:00:00Z", Request: Request{Method: "GET", URL: "https://example.com/a"}},
+		{StartedDateTime: "2024-01-01T00:00:01Z", Request: Request{Method: "GET", URL: "https://example.com/b"}},
+	}
+
+	var buf bytes.Buffer
+	err := writeHAR(&buf, log, len(entries), func(i int) (*Entry, error) { return &entries[i], nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result HAR
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("写出的文件无效: %v\n%s", err, buf.String())
+	}
+	if len(result.Log.Entries) != 2 || result.Log.Entries[1].Request.URL != "https://example.com/b" {
+		t.Errorf("条目不完整: %+v", result.Log.Entries)
+	}
+	if result.Log.Comment != "comment" || string(result.Log.Creator.Custom["_x"]) != string(trap) ||
+		string(result.Log.Pages[0].Custom["_z"]) != string(trap) || string(result.Log.Custom["_log"]) != string(trap) {
+		t.Errorf("log 字段不完整: %s", buf.String())
+	}
+}
+
+// 没有条目时写出空数组
+func TestWriteHAREmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeHAR(&buf, Log{Version: "1.2"}, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"log":{"version":"1.2","creator":{"name":"","version":""},"entries":[]}}`; buf.String() != want {
+		t.Errorf("结果为 %s，应为 %s", buf.String(), want)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 可导入的抓包格式，导入时转换为HAR
+type captureFormat struct {
+	Name       string
+	Extensions []string
+	sniff      func(head []byte) bool // 扩展名无法确定格式时根据文件开头判断
+	// 转换文件，每转换出一个条目就交给 emit，不在内存中保留所有条目
+	convert func(r io.Reader, emit func(*Entry) error) error
+	// 条目不是按开始时间的顺序产生的，加载后按开始时间排序
+	sortByStart bool
+}
+
+var captureFormats = []captureFormat{
+	{"Charles", []string{".chlsj"}, sniffCharles, convertCharles, false},
+	{"Fiddler", []string{".saz"}, sniffZip, convertSAZ, false},
+	{"mitmproxy", []string{".flow", ".mitm", ".mitmdump"}, sniffMitmproxy, convertMitmproxy, false},
+	{"Chrome NetLog", nil, sniffNetLog, convertNetLog, true},
+	{"pcap", []string{".pcap", ".cap"}, sniffPcap, convertPcap, true},
+}
+
+// 上传和命令行支持的文件扩展名
+const captureAccept = ".har,.json,.chlsj,.saz,.flow,.mitm,.mitmdump,.pcap,.cap"
+
+// 判断文件格式，HAR 返回 nil
+func detectCaptureFormat(name string, head []byte) *captureFormat {
+	ext := strings.ToLower(filepath.Ext(name))
+	for i := range captureFormats {
+		for _, e := range captureFormats[i].Extensions {
+			if e == ext {
+				return &captureFormats[i]
+			}
+		}
+	}
+	// HAR 文件开头通常就是 {"log"
+	trimmed := bytes.TrimLeft(head, " \t\r\n\ufeff")
+	if bytes.HasPrefix(trimmed, []byte(`{"log"`)) || bytes.HasPrefix(trimmed, []byte(`{ "log"`)) {
+		return nil
+	}
+	for i := range captureFormats {
+		if captureFormats[i].sniff(head) {
+			return &captureFormats[i]
+		}
+	}
+	return nil
+}
+
+// 加载抓包文件，HAR 文件直接流式解析，其他格式先转换为HAR
+func loadCapture(name string, reader io.Reader, progress *loadProgress) (*harFile, error) {
+	buffered := bufio.NewReaderSize(reader, 64<<10)
+	head, _ := buffered.Peek(4096)
+	format := detectCaptureFormat(name, head)
+	if format == nil {
+		return loadHAR(name, buffered, progress)
+	}
+
+	// 转换结果通过管道交给 loadHAR，与HAR文件使用相同的加载流程，边转换边写入临时文件
+	counter := &countingReader{reader: buffered, progress: progress}
+	pr, pw := io.Pipe()
+	var convertErr error
+	count := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		writer, err := newHARWriter(pw, newImportedHAR(format.Name).Log)
+		if err == nil {
+			err = format.convert(counter, func(entry *Entry) error {
+				count++
+				return writer.write(entry)
+			})
+		}
+		if err == nil {
+			err = writer.close()
+		}
+		convertErr = err
+		pw.CloseWithError(err)
+	}()
+	file, err := loadHAR(name, pr, nil)
+	// loadHAR 提前出错时让转换停止
+	pr.Close()
+	<-done
+
+	if convertErr != nil && !errors.Is(convertErr, io.ErrClosedPipe) {
+		file.Close()
+		return nil, fmt.Errorf("导入 %s 文件失败: %v", format.Name, convertErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		file.Close()
+		return nil, fmt.Errorf("导入 %s 文件失败: 没有找到HTTP请求", format.Name)
+	}
+	if format.sortByStart {
+		file.sortByStart()
+	}
+	file.Size = counter.count
+	if progress != nil {
+		progress.Entries.Store(int64(count))
+	}
+	return file, nil
+}
+
+// 创建导入结果
+func newImportedHAR(format string) *HAR {
+	return &HAR{Log: Log{
+		Version: "1.2",
+		Creator: Creator{Name: "harviewer", Version: "1.0"},
+		Comment: "由 " + format + " 文件导入",
+	}}
+}
+
+// 创建条目，各字段使用规范要求的默认值
+func newImportedEntry(method, rawURL, httpVersion string, started time.Time) Entry {
+	entry := Entry{
+		StartedDateTime: started.Format(time.RFC3339Nano),
+		Request: Request{
+			Method:      method,
+			URL:         rawURL,
+			HTTPVersion: httpVersion,
+			Cookies:     []Cookie{},
+			Headers:     []Header{},
+			QueryString: importedQueryString(rawURL),
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: Response{
+			HTTPVersion: httpVersion,
+			Cookies:     []Cookie{},
+			Headers:     []Header{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+	}
+	if started.IsZero() {
+		entry.StartedDateTime = time.Unix(0, 0).UTC().Format(time.RFC3339Nano)
+	}
+	return entry
+}
+
+// 从URL生成 queryString
+func importedQueryString(rawURL string) []QueryString {
+	params := []QueryString{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return params
+	}
+	for _, field := range parseFormFields(u.RawQuery) {
+		params = append(params, QueryString{Name: field.Name, Value: field.Value})
+	}
+	return params
+}
+
+// 转换 net/http 的头部，按名称排序
+func importedHeaders(header http.Header) []Header {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	headers := []Header{}
+	for _, name := range names {
+		for _, value := range header[name] {
+			headers = append(headers, Header{Name: name, Value: value})
+		}
+	}
+	return headers
+}
+
+// 生成响应内容，不可打印的内容使用 base64 编码
+func importedContent(body []byte, mimeType string) Content {
+	content := Content{Size: int64(len(body)), MimeType: mimeType, Text: string(body)}
+	if !isPrintableText(body) {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+	return content
+}
+
+// 生成请求体，没有请求体时返回 nil
+func importedPostData(body []byte, mimeType string) *PostData {
+	if len(body) == 0 {
+		return nil
+	}
+	postData := &PostData{MimeType: mimeType, Text: string(body)}
+	if baseMimeType(mimeType) == "application/x-www-form-urlencoded" {
+		for _, field := range parseFormFields(postData.Text) {
+			postData.Params = append(postData.Params, Param{Name: field.Name, Value: field.Value})
+		}
+	}
+	return postData
+}
+
+// 两个时间点之间的毫秒数，任一时间未知时返回 -1
+func millisBetween(start, end time.Time) float64 {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return -1
+	}
+	return float64(end.Sub(start).Microseconds()) / 1000
+}
+
+// 根据原始HTTP请求和响应生成条目（用于 Fiddler 和 pcap）
+func entryFromHTTP(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, started time.Time) Entry {
+	rawURL := req.URL.String()
+	if !req.URL.IsAbs() {
+		scheme := "http"
+		if req.TLS != nil {
+			scheme = "https"
+		}
+		rawURL = scheme + "://" + req.Host + req.URL.RequestURI()
+	}
+
+	entry := newImportedEntry(req.Method, rawURL, req.Proto, started)
+	entry.Request.Headers = importedHeaders(req.Header)
+	if req.Host != "" && req.Header.Get("Host") == "" {
+		entry.Request.Headers = append([]Header{{Name: "Host", Value: req.Host}}, entry.Request.Headers...)
+	}
+	entry.Request.PostData = importedPostData(reqBody, req.Header.Get("Content-Type"))
+	entry.Request.BodySize = int64(len(reqBody))
+
+	if resp != nil {
+		entry.Response.Status = resp.StatusCode
+		entry.Response.StatusText = strings.TrimPrefix(resp.Status, fmt.Sprintf("%d ", resp.StatusCode))
+		entry.Response.HTTPVersion = resp.Proto
+		entry.Response.Headers = importedHeaders(resp.Header)
+		entry.Response.Content = importedContent(respBody, resp.Header.Get("Content-Type"))
+		entry.Response.RedirectURL = resp.Header.Get("Location")
+		entry.Response.BodySize = int64(len(respBody))
+	}
+	return entry
+}
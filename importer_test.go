@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 导入 testdata 中的抓包文件，keep 小于 1 时只保留文件的前一部分，模拟被截断的文件
+func loadTestCapture(t *testing.T, name string, keep float64) (*harFile, error) {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := loadCapture(name, bytes.NewReader(data[:int(float64(len(data))*keep)]), nil)
+	if file != nil {
+		t.Cleanup(func() { file.Close() })
+	}
+	return file, err
+}
+
+// 条目摘要：开始时间 方法 URL 状态码 响应内容
+func captureSummary(t *testing.T, file *harFile) []string {
+	t.Helper()
+	var summary []string
+	for i := range file.HAR.Log.Entries {
+		entry, err := file.Entry(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		summary = append(summary, fmt.Sprintf("%s %s %s %d %s", entry.StartedDateTime, entry.Request.Method,
+			entry.Request.URL, entry.Response.Status, entry.Response.Content.Text))
+	}
+	return summary
+}
+
+func TestLoadCapture(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		want   []string
+	}{
+		{"charles.chlsj", "Charles", []string{
+			"2024-01-01T10:00:01+08:00 GET https://example.com/api/users?page=2 200 {\"users\":[]}",
+			"2024-01-01T10:00:02+08:00 POST http://example.com:8080/upload 404 ",
+		}},
+		{"fiddler.saz", "Fiddler", []string{
+			"2024-01-01T10:00:00+08:00 GET https://example.com/index.html 200 <p>hello</p>",
+			"2024-01-01T10:00:01+08:00 POST http://example.com/form 302 ",
+		}},
+		{"mitmproxy.flow", "mitmproxy", []string{
+			"2024-01-01T02:00:00Z GET https://example.com/a 200 first",
+			"2024-01-01T02:00:01.5Z POST https://example.com/b 201 second",
+		}},
+		// 重定向拆分为两个条目，未结束的请求也保留，按开始时间排序
+		{"netlog.json", "Chrome NetLog", []string{
+			"2024-01-01T02:00:00.1Z GET http://example.com/old 301 ",
+			"2024-01-01T02:00:00.2Z GET https://example.com/new 200 <p/>\n",
+			"2024-01-01T02:00:00.21Z POST https://example.com/api 200 ok",
+			"2024-01-01T02:00:00.45Z GET https://example.com/slow 0 ",
+		}},
+		// 乱序和重传的数据段重组后解析，同一连接的两个请求，关闭后迟到的重传被忽略
+		{"http.pcap", "pcap", []string{
+			"2024-01-01T02:00:00Z GET http://example.com/one 200 first",
+			"2024-01-01T02:00:00.15Z GET http://example.org/three 204 ",
+			"2024-01-01T02:00:00.2Z POST http://example.com/two 201 ",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := loadTestCapture(t, test.name, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "由 " + test.format + " 文件导入"; file.HAR.Log.Comment != want {
+				t.Errorf("注释为 %q，应为 %q", file.HAR.Log.Comment, want)
+			}
+			if got := captureSummary(t, file); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("条目为\n%s\n应为\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
+
+// 各格式特有的字段
+func TestLoadCaptureDetails(t *testing.T) {
+	entry := func(name string, index int) Entry {
+		file, err := loadTestCapture(t, name, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry, err := file.Entry(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entry
+	}
+
+	charles := entry("charles.chlsj", 1)
+	if charles.Request.PostData == nil || charles.Request.PostData.Text != "\x00\x01\x02\x03" {
+		t.Errorf("Charles base64 请求体为 %+v", charles.Request.PostData)
+	}
+	if timings := entry("charles.chlsj", 0).Timings; timings.DNS != 5 || timings.Connect != 30 || timings.SSL != 10 || timings.Wait != 90 {
+		t.Errorf("Charles 耗时为 %+v", timings)
+	}
+
+	saz := entry("fiddler.saz", 1)
+	if saz.ServerIPAddress != "93.184.216.34" || saz.Connection != "50123" || saz.Response.RedirectURL != "/done" {
+		t.Errorf("SAZ 会话信息为 %q %q %q", saz.ServerIPAddress, saz.Connection, saz.Response.RedirectURL)
+	}
+	if saz.Request.PostData == nil || len(saz.Request.PostData.Params) != 1 || saz.Request.PostData.Params[0].Value != "go" {
+		t.Errorf("SAZ 表单为 %+v", saz.Request.PostData)
+	}
+
+	flow := entry("mitmproxy.flow", 1)
+	if flow.ServerIPAddress != "93.184.216.34" || flow.Request.PostData == nil || flow.Request.PostData.Text != `{"a":1}` {
+		t.Errorf("mitmproxy 条目为 %q %+v", flow.ServerIPAddress, flow.Request.PostData)
+	}
+
+	netLog := entry("netlog.json", 0)
+	if netLog.Response.RedirectURL != "https://example.com/new" || netLog.Timings.Wait != 50 {
+		t.Errorf("NetLog 重定向为 %q，等待 %v", netLog.Response.RedirectURL, netLog.Timings.Wait)
+	}
+
+	// 连接的第一个请求包含建立连接的时间
+	first, second := entry("http.pcap", 0), entry("http.pcap", 2)
+	if first.Timings.Connect != 20 || first.Time != 100 || first.Connection != "50001" || first.ServerIPAddress != "93.184.216.34" {
+		t.Errorf("pcap 第一个请求为 %+v %v %q %q", first.Timings, first.Time, first.Connection, first.ServerIPAddress)
+	}
+	if second.Timings.Connect != -1 || second.Request.PostData == nil || second.Request.PostData.Text != "data" {
+		t.Errorf("pcap 保持连接的请求为 %+v %+v", second.Timings, second.Request.PostData)
+	}
+}
+
+// 截断的文件：JSON、zip 和 tnetstring 格式报错，NetLog 和 pcap 保留已读取的部分
+func TestLoadCaptureTruncated(t *testing.T) {
+	for _, name := range []string{"charles.chlsj", "fiddler.saz", "mitmproxy.flow"} {
+		if _, err := loadTestCapture(t, name, 0.6); err == nil || !strings.Contains(err.Error(), "导入") {
+			t.Errorf("%s: 截断的文件返回 %v", name, err)
+		}
+	}
+
+	file, err := loadTestCapture(t, "netlog.json", 0.6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"2024-01-01T02:00:00.1Z GET http://example.com/old 301 ",
+		"2024-01-01T02:00:00.2Z GET https://example.com/new 0 ",
+		"2024-01-01T02:00:00.21Z POST https://example.com/api 0 ",
+	}
+	if got := captureSummary(t, file); !reflect.DeepEqual(got, want) {
+		t.Errorf("截断的 NetLog 为\n%s", strings.Join(got, "\n"))
+	}
+
+	// 最后一个数据包不完整时丢弃该数据包
+	file, err = loadTestCapture(t, "http.pcap", 0.6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		"2024-01-01T02:00:00Z GET http://example.com/one 200 first",
+		"2024-01-01T02:00:00.15Z GET http://example.org/three 204 ",
+	}
+	if got := captureSummary(t, file); !reflect.DeepEqual(got, want) {
+		t.Errorf("截断的 pcap 为\n%s", strings.Join(got, "\n"))
+	}
+}
+
+// pcapng 文件和不包含HTTP请求的文件
+func TestLoadCaptureRejected(t *testing.T) {
+	pcapng := make([]byte, 28)
+	binary.LittleEndian.PutUint32(pcapng, pcapngMagic)
+	binary.LittleEndian.PutUint32(pcapng[4:], 28)
+	binary.LittleEndian.PutUint32(pcapng[8:], 0x1a2b3c4d)
+	_, err := loadCapture("capture.pcapng", bytes.NewReader(pcapng), nil)
+	if err == nil || !strings.Contains(err.Error(), "pcapng") {
+		t.Errorf("pcapng 文件返回 %v", err)
+	}
+
+	// 只有 pcap 文件头
+	data, err := os.ReadFile("testdata/http.pcap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadCapture("empty.pcap", bytes.NewReader(data[:24]), nil); err == nil || !strings.Contains(err.Error(), "没有找到HTTP请求") {
+		t.Errorf("空的 pcap 文件返回 %v", err)
+	}
+}
+
+func TestReadTnetstring(t *testing.T) {
+	tests := []struct {
+		input string
+		want  any
+		err   string
+	}{
+		{"5:hello,", "hello", ""},
+		{"2:42#", int64(42), ""},
+		{"3:1.5^", 1.5, ""},
+		{"4:true!", true, ""},
+		{"0:~", nil, ""},
+		{"0:]", []any{}, ""},
+		{"22:1:a;1:1#1:b;7:1:x,0:~]}", map[string]any{"a": int64(1), "b": []any{"x", nil}}, ""},
+		{"8:1:1#1:a;}", nil, "字典的键不是字符串"},
+		{"abc:x,", nil, "长度无效"},
+		{"-1:,", nil, "长度无效"},
+		{"99999999999999:x,", nil, "长度无效"},
+		{"10:short,", nil, "数据不完整"},
+		{"3:abc?", nil, "未知的 tnetstring 类型"},
+		{"5:9:ab,]", nil, "长度无效"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			value, err := readTnetstring(bufio.NewReader(strings.NewReader(test.input)))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("返回 %v %v，应包含错误 %q", value, err, test.err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(value, test.want) {
+				t.Errorf("返回 %#v %v，应为 %#v", value, err, test.want)
+			}
+		})
+	}
+}
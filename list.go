@@ -0,0 +1,394 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 请求列表每页可选的条目数量
+var pageSizes = []int{50, 100, 200, 500}
+
+// 请求列表的查询参数
+type listOptions struct {
+	SessionID string
+	FileID    string
+	Query     string
+	Sort      string
+	Order     string
+	Page      int
+	Size      int
+	Group     bool // 按页面分组
+}
+
+// 分页导航
+type pagination struct {
+	Page  int
+	Pages int
+	Size  int
+	Total int
+	From  int
+	To    int
+	First string
+	Prev  string
+	Next  string
+	Last  string
+	Sizes []pageSizeOption
+}
+
+// 每页数量选项
+type pageSizeOption struct {
+	Size     int
+	URL      string
+	Selected bool
+}
+
+// 从请求中读取列表参数
+func parseListOptions(r *http.Request, sid, fid string) listOptions {
+	query := r.URL.Query()
+	options := listOptions{
+		SessionID: sid,
+		FileID:    fid,
+		Query:     strings.TrimSpace(query.Get("q")),
+		Sort:      query.Get("sort"),
+		Order:     query.Get("order"),
+		Page:      intParam(r, "page", 1),
+		Size:      intParam(r, "size", defaultPageSize),
+		Group:     query.Get("group") == "page",
+	}
+	if options.Order != "desc" {
+		options.Order = "asc"
+	}
+	if options.Page < 1 {
+		options.Page = 1
+	}
+	if options.Size <= 0 || options.Size > maxPageSize {
+		options.Size = defaultPageSize
+	}
+	return options
+}
+
+// 生成列表页面地址，changes 为需要修改的参数
+func (o listOptions) url(changes map[string]string) string {
+	values := url.Values{}
+	values.Set("sid", o.SessionID)
+	values.Set("fid", o.FileID)
+	if o.Query != "" {
+		values.Set("q", o.Query)
+	}
+	if o.Sort != "" {
+		values.Set("sort", o.Sort)
+		values.Set("order", o.Order)
+	}
+	if o.Page > 1 {
+		values.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.Size != defaultPageSize {
+		values.Set("size", strconv.Itoa(o.Size))
+	}
+	if o.Group {
+		values.Set("group", "page")
+	}
+	for key, value := range changes {
+		if value == "" {
+			values.Del(key)
+		} else {
+			values.Set(key, value)
+		}
+	}
+	return "/?" + values.Encode()
+}
+
+// 生成表头排序链接和指示符，点击当前排序列时切换方向
+func (o listOptions) sortLinks(columns ...string) (map[string]string, map[string]string) {
+	links := make(map[string]string)
+	marks := make(map[string]string)
+	for _, column := range columns {
+		order := "asc"
+		marks[column] = "↕"
+		if o.Sort == column {
+			if o.Order == "asc" {
+				order = "desc"
+				marks[column] = "↑"
+			} else {
+				marks[column] = "↓"
+			}
+		}
+		links[column] = o.url(map[string]string{"sort": column, "order": order, "page": ""})
+	}
+	return links, marks
+}
+
+// 对条目序号排序，未指定排序字段时保持原始顺序
+func sortIndexes(entries []Entry, indexes []int, sortBy, order string) {
+	var less func(a, b *Entry) bool
+	switch sortBy {
+	case "method":
+		less = func(a, b *Entry) bool { return a.Request.Method < b.Request.Method }
+	case "url":
+		less = func(a, b *Entry) bool { return a.Request.URL < b.Request.URL }
+	case "status":
+		less = func(a, b *Entry) bool { return a.Response.Status < b.Response.Status }
+	case "size":
+		less = func(a, b *Entry) bool { return a.Response.Content.Size < b.Response.Content.Size }
+	case "time":
+		less = func(a, b *Entry) bool { return a.Time < b.Time }
+	case "start":
+		less = func(a, b *Entry) bool { return a.StartedDateTime < b.StartedDateTime }
+	default:
+		return
+	}
+
+	sort.SliceStable(indexes, func(i, j int) bool {
+		a, b := &entries[indexes[i]], &entries[indexes[j]]
+		if order == "desc" {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+}
+
+// 计算分页，返回当前页的条目序号
+func paginate(indexes []int, o listOptions) ([]int, pagination) {
+	p := pagination{Size: o.Size, Total: len(indexes)}
+	p.Pages = (len(indexes) + o.Size - 1) / o.Size
+	if p.Pages == 0 {
+		p.Pages = 1
+	}
+	p.Page = o.Page
+	if p.Page > p.Pages {
+		p.Page = p.Pages
+	}
+
+	start := (p.Page - 1) * o.Size
+	end := start + o.Size
+	if end > len(indexes) {
+		end = len(indexes)
+	}
+	if start < end {
+		p.From, p.To = start+1, end
+	}
+
+	pageURL := func(page int) string {
+		return o.url(map[string]string{"page": strconv.Itoa(page)})
+	}
+	if p.Page > 1 {
+		p.First = pageURL(1)
+		p.Prev = pageURL(p.Page - 1)
+	}
+	if p.Page < p.Pages {
+		p.Next = pageURL(p.Page + 1)
+		p.Last = pageURL(p.Pages)
+	}
+	for _, size := range pageSizes {
+		p.Sizes = append(p.Sizes, pageSizeOption{
+			Size:     size,
+			URL:      o.url(map[string]string{"size": strconv.Itoa(size), "page": ""}),
+			Selected: size == o.Size,
+		})
+	}
+	return indexes[start:end], p
+}
+
+// 按页面分组时的页面信息，统计的是所有满足过滤条件的条目
+type pageGroup struct {
+	ID     string
+	Title  string
+	OnLoad float64
+	Count  int
+	Size   int64
+	Time   float64
+}
+
+// 请求列表中的一行，Group 不为空时为页面标题行
+type listRow struct {
+	Index int
+	Group *pageGroup
+}
+
+// 页面选择器选项
+type pageOption struct {
+	Title    string
+	URL      string
+	Selected bool
+}
+
+// 按页面顺序稳定排序条目序号，页面内保持原有顺序，不属于任何页面的条目排在最后
+func groupByPage(harData *HAR, indexes []int) map[string]*pageGroup {
+	order := make(map[string]int)
+	groups := make(map[string]*pageGroup)
+	for i, page := range harData.Log.Pages {
+		order[page.ID] = i
+		groups[page.ID] = &pageGroup{ID: page.ID, Title: page.Title, OnLoad: page.PageTimings.OnLoad}
+	}
+	position := func(pageref string) int {
+		if i, ok := order[pageref]; ok {
+			return i
+		}
+		return len(order)
+	}
+
+	entries := harData.Log.Entries
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return position(entries[indexes[i]].Pageref) < position(entries[indexes[j]].Pageref)
+	})
+	for _, i := range indexes {
+		entry := &entries[i]
+		group, ok := groups[entry.Pageref]
+		if !ok {
+			// 未知页面的条目和没有页面的条目归为一组
+			group, ok = groups[""]
+			if !ok {
+				group = &pageGroup{Title: "其他请求", OnLoad: -1}
+				groups[""] = group
+			}
+			groups[entry.Pageref] = group
+		}
+		group.Count++
+		group.Size += max(entry.Response.Content.Size, 0)
+		group.Time += max(entry.Time, 0)
+	}
+	return groups
+}
+
+// 生成当前页的行，分组时在每个页面的第一个条目前插入标题行
+func listRows(entries []Entry, indexes []int, groups map[string]*pageGroup) []listRow {
+	rows := make([]listRow, 0, len(indexes))
+	var current *pageGroup
+	for _, i := range indexes {
+		if groups != nil {
+			if group := groups[entries[i].Pageref]; group != current {
+				current = group
+				rows = append(rows, listRow{Index: -1, Group: group})
+			}
+		}
+		rows = append(rows, listRow{Index: i})
+	}
+	return rows
+}
+
+// 生成页面选择器选项，选择页面时替换查询中已有的 page: 条件
+func (o listOptions) pageOptions(pages []Page) []pageOption {
+	var terms []string
+	selected := ""
+	for _, token := range splitQuery(o.Query) {
+		if value, ok := strings.CutPrefix(token, "page:"); ok {
+			selected = value
+			continue
+		}
+		terms = append(terms, quoteFilterTerm(token))
+	}
+	query := func(page string) string {
+		if page == "" {
+			return strings.Join(terms, " ")
+		}
+		return strings.Join(append(terms[:len(terms):len(terms)], quoteFilterTerm("page:"+page)), " ")
+	}
+
+	options := []pageOption{{Title: "全部页面", URL: o.url(map[string]string{"q": query(""), "page": ""}), Selected: selected == ""}}
+	for _, page := range pages {
+		title := page.Title
+		if title == "" {
+			title = page.ID
+		}
+		options = append(options, pageOption{
+			Title:    title,
+			URL:      o.url(map[string]string{"q": query(page.ID), "page": ""}),
+			Selected: selected == page.ID,
+		})
+	}
+	return options
+}
+
+// 分页导航片段，显示在请求列表的上方和下方
+var pagerTemplate = `<div class="pager">
+    {{if .Prev}}<a href="{{.First}}">首页</a><a href="{{.Prev}}">上一页</a>{{else}}<span class="disabled">首页</span><span class="disabled">上一页</span>{{end}}
+    <span>第 {{.Page}} / {{.Pages}} 页{{if .Total}}，第 {{.From}}-{{.To}} 个，共 {{.Total}} 个{{end}}</span>
+    {{if .Next}}<a href="{{.Next}}">下一页</a><a href="{{.Last}}">末页</a>{{else}}<span class="disabled">下一页</span><span class="disabled">末页</span>{{end}}
+    <span>每页
+        <select onchange="location.href = this.value">
+            {{range .Sizes}}<option value="{{.URL}}"{{if .Selected}} selected{{end}}>{{.Size}}</option>{{end}}
+        </select>
+    个</span>
+</div>`
+
+// 条目详情片段，展开时按需加载，请求体和响应体再次按需加载
+var entryDetailTemplate = `<div style="padding: 15px; background-color: #e0e0e0; border-radius: 5px;">
+    <h3>请求详情</h3>
+    <p><strong>URL:</strong> {{.Entry.Request.URL}}</p>
+    <p><strong>方法:</strong> {{.Entry.Request.Method}}</p>
+    <p><strong>状态:</strong> {{.Entry.Response.Status}} {{.Entry.Response.StatusText}}</p>
+    <p><strong>耗时:</strong> {{printf "%.2f" .Entry.Time}} ms</p>
+    {{template "entry-url" .}}
+    
+    <h4>复制为</h4>
+    <div class="snippet-view">
+        {{range .SnippetFormats}}<button type="button" class="btn snippet-btn" onclick="copySnippet(this, '{{.Key}}')">{{.Name}}</button>{{end}}
+        <span class="snippet-status"></span>
+        <textarea class="snippet-text" readonly style="display: none;" data-src="/snippet?sid={{.SessionID}}&fid={{.FileID}}&index={{.Index}}"></textarea>
+    </div>
+    
+    <h4>重放请求</h4>
+    <form class="replay-form" method="post" action="/replay?sid={{.SessionID}}&fid={{.FileID}}&index={{.Index}}" onsubmit="replayEntry(this); return false;">
+        <input type="text" name="target" class="replay-target" placeholder="目标地址，如 http://localhost:8080，留空发送到原地址">
+        <input type="submit" value="重放" class="btn upload-btn">
+        <div class="replay-output"></div>
+    </form>
+    
+    <h4>请求头</h4>
+    <ul>
+        {{range $header := .Entry.Request.Headers}}
+        <li>{{$header.Name}}: {{$header.Value}}</li>
+        {{end}}
+    </ul>
+    
+    <h4>请求体</h4>
+    <div class="lazy-section" data-src="/request-body?sid={{.SessionID}}&fid={{.FileID}}&index={{.Index}}">加载中...</div>
+    
+    <h4>响应头</h4>
+    <ul>
+        {{range $header := .Entry.Response.Headers}}
+        <li>{{$header.Name}}: {{$header.Value}}</li>
+        {{end}}
+    </ul>
+    {{template "entry-cookies" .}}
+    
+    <h4>响应体</h4>
+    <div class="lazy-section" data-src="/response-body?sid={{.SessionID}}&fid={{.FileID}}&index={{.Index}}">加载中...</div>
+</div>`
+
+// 条目详情处理函数
+func entryDetailHandler(w http.ResponseWriter, r *http.Request) {
+	sid, file, index, ok := entryIndexFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tmpl, err := template.New("entry-detail").Parse(entryDetailTemplate)
+	if err == nil {
+		_, err = tmpl.New("entry-cookies").Parse(entryCookiesTemplate)
+	}
+	if err == nil {
+		_, err = tmpl.New("entry-url").Parse(entryURLTemplate)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("解析模板失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	entry := &file.HAR.Log.Entries[index]
+	tmpl.Execute(w, map[string]interface{}{
+		"SessionID":      sid,
+		"FileID":         file.ID,
+		"Index":          index,
+		"Entry":          entry,
+		"SnippetFormats": snippetFormats,
+		"SentCookies":    entrySentCookies(entry),
+		"SetCookies":     entrySetCookies(entry),
+		"URLParts":       buildURLParts(sid, file.ID, &entry.Request),
+	})
+}
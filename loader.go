@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 请求体或响应体在临时文件中的位置
+type spillRef struct {
+	offset int64
+	length int64
+}
+
+// 单个条目的请求体和响应体位置
+type entryBodies struct {
+	request  spillRef
+	response spillRef
+}
+
+// 已加载的HAR文件
+// 内存中只保留不含请求体、响应体内容的条目索引，内容写入磁盘临时文件，需要时再读取
+type harFile struct {
+	ID     string
+	Name   string
+	Size   int64
+	HAR    *HAR
+	bodies []entryBodies
+	spill  *os.File
+
+	// 正在读取临时文件的请求数，关闭时有读取方则等最后一个读取方释放后再关闭
+	mu      sync.Mutex
+	readers int
+	closing bool
+
+	// 瀑布图时间轴，第一次使用时计算
+	scaleOnce sync.Once
+	scale     *waterfallScale
+}
+
+// 加载进度
+type loadProgress struct {
+	Read    atomic.Int64
+	Total   atomic.Int64
+	Entries atomic.Int64
+}
+
+// 统计读取字节数的Reader
+type countingReader struct {
+	reader   io.Reader
+	count    int64
+	progress *loadProgress
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count += int64(n)
+	if c.progress != nil {
+		c.progress.Read.Add(int64(n))
+	}
+	return n, err
+}
+
+// 获取完整条目，包括从临时文件中读取的请求体和响应体
+func (f *harFile) Entry(index int) (Entry, error) {
+	entry := f.HAR.Log.Entries[index]
+	bodies := f.bodies[index]
+
+	text, err := f.readSpill(bodies.response)
+	if err != nil {
+		return entry, err
+	}
+	entry.Response.Content.Text = text
+
+	if entry.Request.PostData != nil {
+		postData := *entry.Request.PostData
+		if postData.Text, err = f.readSpill(bodies.request); err != nil {
+			return entry, err
+		}
+		entry.Request.PostData = &postData
+	}
+	return entry, nil
+}
+
+// 按开始时间排序条目，开始时间相同时保持原来的顺序
+func (f *harFile) sortByStart() {
+	entries := f.HAR.Log.Entries
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sortByStartTime(entries, order)
+
+	sorted := make([]Entry, len(entries))
+	bodies := make([]entryBodies, len(entries))
+	for i, index := range order {
+		sorted[i] = entries[index]
+		bodies[i] = f.bodies[index]
+	}
+	f.HAR.Log.Entries, f.bodies = sorted, bodies
+}
+
+// 从临时文件读取内容
+func (f *harFile) readSpill(ref spillRef) (string, error) {
+	if ref.length == 0 {
+		return "", nil
+	}
+	buf := make([]byte, ref.length)
+	if _, err := f.spill.ReadAt(buf, ref.offset); err != nil {
+		return "", fmt.Errorf("读取临时文件失败: %v", err)
+	}
+	return string(buf), nil
+}
+
+// 登记一个读取方，文件已关闭时返回 false
+func (f *harFile) acquire() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closing {
+		return false
+	}
+	f.readers++
+	return true
+}
+
+// 释放读取方，文件已关闭且没有其他读取方时删除临时文件
+func (f *harFile) release() {
+	f.mu.Lock()
+	f.readers--
+	last := f.closing && f.readers == 0
+	f.mu.Unlock()
+	if last {
+		f.removeSpill()
+	}
+}
+
+// 关闭文件，仍有读取方时推迟到最后一个读取方释放后再删除临时文件
+func (f *harFile) Close() error {
+	if f == nil || f.spill == nil {
+		return nil
+	}
+	f.mu.Lock()
+	if f.closing {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closing = true
+	busy := f.readers > 0
+	f.mu.Unlock()
+	if busy {
+		return nil
+	}
+	return f.removeSpill()
+}
+
+// 关闭并删除临时文件
+func (f *harFile) removeSpill() error {
+	name := f.spill.Name()
+	f.spill.Close()
+	return os.Remove(name)
+}
+
+// 临时文件写入器
+type spillWriter struct {
+	writer *bufio.Writer
+	offset int64
+}
+
+func (s *spillWriter) write(text string) (spillRef, error) {
+	if text == "" {
+		return spillRef{}, nil
+	}
+	ref := spillRef{offset: s.offset, length: int64(len(text))}
+	if _, err := s.writer.WriteString(text); err != nil {
+		return spillRef{}, err
+	}
+	s.offset += ref.length
+	return ref, nil
+}
+
+// 读取下一个分隔符并检查是否符合预期
+func expectDelim(decoder *json.Decoder, want json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("HAR格式错误: 期望 %v，实际为 %v", want, token)
+	}
+	return nil
+}
+
+// 读取对象的键
+func readKey(decoder *json.Decoder) (string, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := token.(string)
+	if !ok {
+		return "", fmt.Errorf("HAR格式错误: 期望字段名，实际为 %v", token)
+	}
+	return key, nil
+}
+
+// 流式加载HAR文件，逐个解析 log.entries 中的条目
+func loadHAR(name string, reader io.Reader, progress *loadProgress) (*harFile, error) {
+	spill, err := os.CreateTemp("", "harviewer-*.body")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+
+	file := &harFile{Name: name, HAR: &HAR{}, spill: spill}
+	counter := &countingReader{reader: reader, progress: progress}
+	writer := &spillWriter{writer: bufio.NewWriter(spill)}
+
+	err = decodeHAR(json.NewDecoder(counter), file, writer, progress)
+	if err == nil {
+		err = writer.writer.Flush()
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	file.Size = counter.count
+	return file, nil
+}
+
+// 解析HAR顶层对象
+func decodeHAR(decoder *json.Decoder, file *harFile, writer *spillWriter, progress *loadProgress) error {
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+
+	foundLog := false
+	for decoder.More() {
+		key, err := readKey(decoder)
+		if err != nil {
+			return err
+		}
+		if key != "log" {
+			var skip json.RawMessage
+			if err := decoder.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := decodeLog(decoder, file, writer, progress); err != nil {
+			return err
+		}
+		foundLog = true
+	}
+	if !foundLog {
+		return fmt.Errorf("HAR格式错误: 缺少 log 字段")
+	}
+	return expectDelim(decoder, '}')
+}
+
+// 解析 log 对象
+func decodeLog(decoder *json.Decoder, file *harFile, writer *spillWriter, progress *loadProgress) error {
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+
+	log := &file.HAR.Log
+	for decoder.More() {
+		key, err := readKey(decoder)
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "version":
+			err = decoder.Decode(&log.Version)
+		case "creator":
+			err = decoder.Decode(&log.Creator)
+		case "browser":
+			err = decoder.Decode(&log.Browser)
+		case "pages":
+			err = decoder.Decode(&log.Pages)
+		case "comment":
+			err = decoder.Decode(&log.Comment)
+		case "entries":
+			err = decodeEntries(decoder, file, writer, progress)
+		default:
+			var raw json.RawMessage
+			err = decoder.Decode(&raw)
+			if err == nil && strings.HasPrefix(key, "_") {
+				if log.Custom == nil {
+					log.Custom = make(CustomFields)
+				}
+				log.Custom[key] = raw
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return expectDelim(decoder, '}')
+}
+
+// 逐个解析条目，并将请求体和响应体写入临时文件
+func decodeEntries(decoder *json.Decoder, file *harFile, writer *spillWriter, progress *loadProgress) error {
+	if err := expectDelim(decoder, '['); err != nil {
+		return err
+	}
+
+	for decoder.More() {
+		var entry Entry
+		if err := decoder.Decode(&entry); err != nil {
+			return err
+		}
+
+		var bodies entryBodies
+		var err error
+		if bodies.response, err = writer.write(entry.Response.Content.Text); err != nil {
+			return fmt.Errorf("写入临时文件失败: %v", err)
+		}
+		entry.Response.Content.Text = ""
+		if entry.Request.PostData != nil {
+			if bodies.request, err = writer.write(entry.Request.PostData.Text); err != nil {
+				return fmt.Errorf("写入临时文件失败: %v", err)
+			}
+			entry.Request.PostData.Text = ""
+		}
+
+		file.HAR.Log.Entries = append(file.HAR.Log.Entries, entry)
+		file.bodies = append(file.bodies, bodies)
+		if progress != nil {
+			progress.Entries.Add(1)
+		}
+	}
+	return expectDelim(decoder, ']')
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"os"
 	"os/exec"
 	"runtime"
 
@@ -18,6 +19,11 @@ var (
 )
 
 func main() {
+	// 带参数运行时进入命令行模式，不创建GUI窗口
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
 	// 设置路由
 	setupRoutes()
 
@@ -48,17 +54,13 @@ func main() {
 		}
 
 		// 启动HTTP服务器
-		go func() {
-			httpServer = &http.Server{
-				Addr:    ":" + port,
-				Handler: nil, // 使用默认的http.ServeMux
-			}
-
+		httpServer = newServer(port, nil)
+		go func(server *http.Server) {
 			fmt.Printf("HAR Viewer 已启动，访问地址: http://localhost:%s\n", port)
-			if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 				fmt.Printf("启动服务器失败: %v\n", err)
 			}
-		}()
+		}(httpServer)
 
 		// 等待服务器启动，然后自动打开浏览器
 		url := fmt.Sprintf("http://localhost:%s", port)
@@ -138,6 +140,14 @@ func main() {
 	myWindow.ShowAndRun()
 }
 
+// 创建Web服务，handler 为 nil 时使用默认的http.ServeMux
+func newServer(port string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:    ":" + port,
+		Handler: handler,
+	}
+}
+
 // 打开URL
 func openURL(url string) {
 	var cmd string
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"time"
+)
+
+// mitmproxy 的流文件由连续的 tnetstring 组成，格式为 <长度>:<数据><类型>
+// 类型: , 字节串  ; 字符串  # 整数  ^ 浮点数  ! 布尔  ~ 空  } 字典  ] 列表
+
+// 单个 tnetstring 的最大长度，不超过上传文件的大小限制
+const maxTnetstringSize = maxUploadSize
+
+// 文件以 "<长度>:" 开头且包含 request 字段
+func sniffMitmproxy(head []byte) bool {
+	i := 0
+	for i < len(head) && i < 10 && head[i] >= '0' && head[i] <= '9' {
+		i++
+	}
+	return i > 0 && i < len(head) && head[i] == ':' && bytes.Contains(head, []byte("7:request"))
+}
+
+// 从流中读取一个 tnetstring，文件结束时返回 io.EOF
+func readTnetstring(r *bufio.Reader) (any, error) {
+	prefix, err := r.ReadString(':')
+	if err != nil {
+		if err == io.EOF && prefix == "" {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("tnetstring 长度读取失败: %v", err)
+	}
+	size, err := strconv.ParseInt(prefix[:len(prefix)-1], 10, 64)
+	if err != nil || size < 0 || size > maxTnetstringSize {
+		return nil, fmt.Errorf("tnetstring 长度无效: %q", prefix)
+	}
+	// 随读取的数据增长缓冲区，长度前缀伪造得很大时也不会预先分配
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, size+1); err != nil {
+		return nil, fmt.Errorf("tnetstring 数据不完整: %v", err)
+	}
+	data := buf.Bytes()
+	return parseTnetPayload(data[:size], data[size])
+}
+
+// 解析缓冲区开头的 tnetstring，返回值和剩余部分
+func parseTnetstring(data []byte) (any, []byte, error) {
+	colon := -1
+	for i := 0; i < len(data) && i < 12; i++ {
+		if data[i] == ':' {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return nil, nil, errors.New("tnetstring 格式错误")
+	}
+	size, err := strconv.Atoi(string(data[:colon]))
+	end := colon + 1 + size
+	if err != nil || size < 0 || end >= len(data) {
+		return nil, nil, errors.New("tnetstring 长度无效")
+	}
+	value, err := parseTnetPayload(data[colon+1:end], data[end])
+	return value, data[end+1:], err
+}
+
+// 按类型解析数据，字节串和字符串都返回 string
+func parseTnetPayload(payload []byte, kind byte) (any, error) {
+	switch kind {
+	case ',', ';':
+		return string(payload), nil
+	case '#':
+		return strconv.ParseInt(string(payload), 10, 64)
+	case '^':
+		return strconv.ParseFloat(string(payload), 64)
+	case '!':
+		return string(payload) == "true", nil
+	case '~':
+		return nil, nil
+	case ']':
+		list := []any{}
+		for len(payload) > 0 {
+			value, rest, err := parseTnetstring(payload)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+			payload = rest
+		}
+		return list, nil
+	case '}':
+		dict := map[string]any{}
+		for len(payload) > 0 {
+			key, rest, err := parseTnetstring(payload)
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("tnetstring 字典的键不是字符串: %v", key)
+			}
+			value, rest, err := parseTnetstring(rest)
+			if err != nil {
+				return nil, err
+			}
+			dict[name] = value
+			payload = rest
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("未知的 tnetstring 类型: %q", kind)
+}
+
+// 读取字典中的字段
+func tnetDict(dict map[string]any, key string) map[string]any {
+	value, _ := dict[key].(map[string]any)
+	return value
+}
+
+func tnetString(dict map[string]any, key string) string {
+	value, _ := dict[key].(string)
+	return value
+}
+
+func tnetNumber(dict map[string]any, key string) float64 {
+	switch value := dict[key].(type) {
+	case int64:
+		return float64(value)
+	case float64:
+		return value
+	}
+	return 0
+}
+
+// 秒级时间戳转换为时间，0 表示未记录
+func tnetTime(dict map[string]any, key string) time.Time {
+	seconds := tnetNumber(dict, key)
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(int64(math.Round(seconds * 1e6)))
+}
+
+// 头部列表，每项为 [名称, 值]
+func tnetHeaders(dict map[string]any) []Header {
+	headers := []Header{}
+	list, _ := dict["headers"].([]any)
+	for _, item := range list {
+		pair, ok := item.([]any)
+		if !ok || len(pair) != 2 {
+			continue
+		}
+		name, _ := pair[0].(string)
+		value, _ := pair[1].(string)
+		headers = append(headers, Header{Name: name, Value: value})
+	}
+	return headers
+}
+
+// 转换 mitmproxy 保存的流文件，逐个读取流
+func convertMitmproxy(r io.Reader, emit func(*Entry) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		value, err := readTnetstring(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		flow, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("流格式错误: 期望字典")
+		}
+		// 只导入HTTP流，跳过 TCP、UDP 等
+		if kind := tnetString(flow, "type"); kind != "" && kind != "http" {
+			continue
+		}
+		if request := tnetDict(flow, "request"); request != nil {
+			entry := mitmproxyEntry(flow, request)
+			if err := emit(&entry); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// 转换单个HTTP流
+func mitmproxyEntry(flow, request map[string]any) Entry {
+	scheme := tnetString(request, "scheme")
+	host := tnetString(request, "host")
+	if port := int(tnetNumber(request, "port")); port > 0 && !(scheme == "http" && port == 80) && !(scheme == "https" && port == 443) {
+		host = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	rawURL := scheme + "://" + host + tnetString(request, "path")
+
+	started := tnetTime(request, "timestamp_start")
+	entry := newImportedEntry(tnetString(request, "method"), rawURL, tnetString(request, "http_version"), started)
+	entry.Request.Headers = tnetHeaders(request)
+	content := []byte(tnetString(request, "content"))
+	entry.Request.PostData = importedPostData(content, headerValue(entry.Request.Headers, "Content-Type"))
+	entry.Request.BodySize = int64(len(content))
+	requestDone := tnetTime(request, "timestamp_end")
+	entry.Timings.Send = max(millisBetween(started, requestDone), 0)
+
+	if serverConn := tnetDict(flow, "server_conn"); serverConn != nil {
+		for _, key := range []string{"peername", "ip_address", "address"} {
+			if address, ok := serverConn[key].([]any); ok && len(address) > 0 {
+				entry.ServerIPAddress, _ = address[0].(string)
+				break
+			}
+		}
+		// 为该请求新建的连接才记录连接耗时
+		connected := tnetTime(serverConn, "timestamp_start")
+		if !connected.IsZero() && !connected.Before(started) {
+			tcpSetup := tnetTime(serverConn, "timestamp_tcp_setup")
+			entry.Timings.Connect = millisBetween(connected, tcpSetup)
+			if tlsSetup := tnetTime(serverConn, "timestamp_tls_setup"); !tlsSetup.IsZero() {
+				entry.Timings.SSL = millisBetween(tcpSetup, tlsSetup)
+				entry.Timings.Connect = millisBetween(connected, tlsSetup)
+			}
+		}
+	}
+
+	finished := requestDone
+	if response := tnetDict(flow, "response"); response != nil {
+		entry.Response.Status = int(tnetNumber(response, "status_code"))
+		entry.Response.StatusText = tnetString(response, "reason")
+		entry.Response.HTTPVersion = tnetString(response, "http_version")
+		entry.Response.Headers = tnetHeaders(response)
+		body := []byte(tnetString(response, "content"))
+		entry.Response.Content = importedContent(body, headerValue(entry.Response.Headers, "Content-Type"))
+		entry.Response.BodySize = int64(len(body))
+		entry.Response.RedirectURL = headerValue(entry.Response.Headers, "Location")
+
+		firstByte := tnetTime(response, "timestamp_start")
+		finished = tnetTime(response, "timestamp_end")
+		entry.Timings.Wait = max(millisBetween(requestDone, firstByte), 0)
+		entry.Timings.Receive = max(millisBetween(firstByte, finished), 0)
+	}
+	if flowError := tnetDict(flow, "error"); flowError != nil {
+		entry.Comment = tnetString(flowError, "msg")
+	}
+	entry.Time = max(millisBetween(started, finished), 0)
+	return entry
+}
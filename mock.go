@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mock服务查看未匹配请求的地址
+const mockReportPath = "/__harviewer/unmatched"
+
+// 回放响应时不复制的响应头，内容已解码并由 net/http 重新计算长度
+var skippedMockHeaders = map[string]bool{
+	"content-length":    true,
+	"content-encoding":  true,
+	"transfer-encoding": true,
+	"connection":        true,
+	"keep-alive":        true,
+}
+
+// Mock服务的匹配规则，默认比较请求方法、路径和查询参数（不区分参数顺序）
+type mockRules struct {
+	IgnoreQuery bool `json:"ignoreQuery"` // 不比较查询参数
+	MatchHost   bool `json:"matchHost"`   // 同时比较主机名
+	MatchBody   bool `json:"matchBody"`   // 同时比较请求体
+}
+
+// 未匹配的请求
+type unmatchedRequest struct {
+	Method string    `json:"method"`
+	URL    string    `json:"url"`
+	Count  int       `json:"count"`
+	Last   time.Time `json:"last"`
+}
+
+// Mock服务状态
+type mockStatus struct {
+	ID        string             `json:"id"`
+	Port      string             `json:"port"`
+	FileName  string             `json:"file"`
+	Rules     mockRules          `json:"rules"`
+	Started   time.Time          `json:"started"`
+	Matched   int                `json:"matched"`
+	Unmatched []unmatchedRequest `json:"unmatched"`
+}
+
+// 使用HAR文件中的响应回答请求的Mock服务
+// 同一请求录制了多次时按顺序依次返回，用完后重复返回最后一次
+type mockServer struct {
+	ID        string
+	SessionID string
+	Port      string
+	Rules     mockRules
+	Started   time.Time
+	file      *harFile
+	server    *http.Server
+	routes    map[string][]int
+	log       io.Writer // 不为空时输出每个请求的匹配结果
+
+	mu        sync.Mutex
+	hits      map[string]int
+	matched   int
+	unmatched map[string]*unmatchedRequest
+}
+
+// 生成匹配键
+func (rules mockRules) key(method, host, path, query, body string) string {
+	if path == "" {
+		path = "/"
+	}
+	parts := []string{strings.ToUpper(method), path}
+	if !rules.IgnoreQuery {
+		// 重新编码后参数按名称排序
+		values, _ := url.ParseQuery(query)
+		parts = append(parts, values.Encode())
+	}
+	if rules.MatchHost {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		parts = append(parts, strings.ToLower(host))
+	}
+	if rules.MatchBody {
+		parts = append(parts, body)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// 创建Mock服务，按匹配规则为所有条目建立索引
+func newMockServer(file *harFile, rules mockRules) (*mockServer, error) {
+	m := &mockServer{
+		ID:        newID(),
+		Rules:     rules,
+		file:      file,
+		routes:    make(map[string][]int),
+		hits:      make(map[string]int),
+		unmatched: make(map[string]*unmatchedRequest),
+	}
+	for i, entry := range file.HAR.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			continue
+		}
+		var body string
+		if rules.MatchBody {
+			full, err := file.Entry(i)
+			if err != nil {
+				return nil, err
+			}
+			if full.Request.PostData != nil {
+				body = full.Request.PostData.Text
+			}
+		}
+		key := rules.key(entry.Request.Method, u.Host, u.Path, u.RawQuery, body)
+		m.routes[key] = append(m.routes[key], i)
+	}
+	return m, nil
+}
+
+// 处理请求，返回匹配条目录制的状态码、响应头和响应体
+func (m *mockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == mockReportPath {
+		writeAPIJSON(w, m.status())
+		return
+	}
+
+	var body string
+	if m.Rules.MatchBody {
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxReplayBodySize))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("读取请求体失败: %v", err), http.StatusBadRequest)
+			return
+		}
+		body = string(data)
+	}
+
+	key := m.Rules.key(r.Method, r.Host, r.URL.Path, r.URL.RawQuery, body)
+	m.mu.Lock()
+	indexes := m.routes[key]
+	index := -1
+	if len(indexes) > 0 {
+		index = indexes[min(m.hits[key], len(indexes)-1)]
+		m.hits[key]++
+		m.matched++
+	} else {
+		requestURL := r.URL.RequestURI()
+		u, ok := m.unmatched[r.Method+" "+requestURL]
+		if !ok {
+			u = &unmatchedRequest{Method: r.Method, URL: requestURL}
+			m.unmatched[r.Method+" "+requestURL] = u
+		}
+		u.Count++
+		u.Last = time.Now()
+	}
+	m.mu.Unlock()
+
+	if index < 0 {
+		m.logf("%s %s -> 未匹配\n", r.Method, r.URL.RequestURI())
+		http.Error(w, fmt.Sprintf("harviewer mock: 未找到匹配的请求 %s %s", r.Method, r.URL.RequestURI()), http.StatusNotFound)
+		return
+	}
+
+	entry, err := m.file.Entry(index)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("读取响应失败: %v", err), http.StatusBadGateway)
+		return
+	}
+	// 录制时请求未完成
+	if entry.Response.Status <= 0 {
+		m.logf("%s %s -> #%d 请求未完成\n", r.Method, r.URL.RequestURI(), index)
+		http.Error(w, "harviewer mock: 录制的请求未完成", http.StatusBadGateway)
+		return
+	}
+
+	data, _, err := decodeContent(entry.Response.Content, entry.Response.Headers)
+	if err != nil {
+		data = []byte(entry.Response.Content.Text)
+	}
+	for _, header := range entry.Response.Headers {
+		if strings.HasPrefix(header.Name, ":") || skippedMockHeaders[strings.ToLower(header.Name)] {
+			continue
+		}
+		w.Header().Add(header.Name, header.Value)
+	}
+	w.WriteHeader(entry.Response.Status)
+	w.Write(data)
+	m.logf("%s %s -> #%d %d\n", r.Method, r.URL.RequestURI(), index, entry.Response.Status)
+}
+
+func (m *mockServer) logf(format string, args ...interface{}) {
+	if m.log != nil {
+		fmt.Fprintf(m.log, format, args...)
+	}
+}
+
+// 获取服务状态，未匹配的请求按次数从多到少排列
+func (m *mockServer) status() mockStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := mockStatus{
+		ID:        m.ID,
+		Port:      m.Port,
+		FileName:  m.file.Name,
+		Rules:     m.Rules,
+		Started:   m.Started,
+		Matched:   m.matched,
+		Unmatched: []unmatchedRequest{},
+	}
+	for _, u := range m.unmatched {
+		status.Unmatched = append(status.Unmatched, *u)
+	}
+	sort.Slice(status.Unmatched, func(i, j int) bool {
+		a, b := status.Unmatched[i], status.Unmatched[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Method+" "+a.URL < b.Method+" "+b.URL
+	})
+	return status
+}
+
+// 在指定端口上监听，端口被占用时直接返回错误
+func (m *mockServer) listen(port string) (net.Listener, error) {
+	m.Port = port
+	m.server = newServer(port, m)
+	ln, err := net.Listen("tcp", m.server.Addr)
+	if err != nil {
+		return nil, err
+	}
+	m.Started = time.Now()
+	return ln, nil
+}
+
+// Web界面启动的Mock服务
+var (
+	mockServersMu sync.Mutex
+	mockServers   []*mockServer
+)
+
+// 启动Mock服务并在后台运行
+func startMock(sid string, file *harFile, port string, rules mockRules) (*mockServer, error) {
+	m, err := newMockServer(file, rules)
+	if err != nil {
+		return nil, err
+	}
+	m.SessionID = sid
+	// Mock服务运行期间持续读取文件，停止后才释放
+	if !file.acquire() {
+		return nil, fmt.Errorf("文件已关闭")
+	}
+	ln, err := m.listen(port)
+	if err != nil {
+		file.release()
+		return nil, err
+	}
+
+	mockServersMu.Lock()
+	mockServers = append(mockServers, m)
+	mockServersMu.Unlock()
+
+	go func() {
+		if err := m.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("Mock服务异常退出: %v\n", err)
+		}
+	}()
+	return m, nil
+}
+
+// 停止会话中的Mock服务，id 或 fid 为空时不作为条件
+func stopMocks(sid, fid, id string) {
+	mockServersMu.Lock()
+	var stopped []*mockServer
+	running := make([]*mockServer, 0, len(mockServers))
+	for _, m := range mockServers {
+		if m.SessionID == sid && (fid == "" || m.file.ID == fid) && (id == "" || m.ID == id) {
+			stopped = append(stopped, m)
+			continue
+		}
+		running = append(running, m)
+	}
+	mockServers = running
+	mockServersMu.Unlock()
+
+	for _, m := range stopped {
+		m.server.Close()
+		m.file.release()
+	}
+}
+
+// 获取文件正在运行的Mock服务状态
+func fileMocks(sid, fid string) []mockStatus {
+	mockServersMu.Lock()
+	defer mockServersMu.Unlock()
+	var statuses []mockStatus
+	for _, m := range mockServers {
+		if m.SessionID == sid && m.file.ID == fid {
+			statuses = append(statuses, m.status())
+		}
+	}
+	return statuses
+}
+
+// 启动Mock服务处理函数
+func mockStartHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "请使用POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+	sid, file, ok := fileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	mockPort := strings.TrimSpace(r.FormValue("port"))
+	if mockPort == "" {
+		mockPort = "9000"
+	}
+	rules := mockRules{
+		IgnoreQuery: r.FormValue("ignoreQuery") != "",
+		MatchHost:   r.FormValue("matchHost") != "",
+		MatchBody:   r.FormValue("matchBody") != "",
+	}
+	if _, err := startMock(sid, file, mockPort, rules); err != nil {
+		http.Error(w, fmt.Sprintf("启动Mock服务失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, sessionURL(sid, file.ID), http.StatusFound)
+}
+
+// 停止Mock服务处理函数
+func mockStopHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "请使用POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+	sid := sessionFromRequest(r)
+	if sid == "" {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	fid := r.URL.Query().Get("fid")
+	stopMocks(sid, fid, r.FormValue("id"))
+	http.Redirect(w, r, sessionURL(sid, fid), http.StatusFound)
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Chrome NetLog（chrome://net-export 导出）的常量表
+type netLogConstants struct {
+	LogEventTypes  map[string]int  `json:"logEventTypes"`
+	LogSourceType  map[string]int  `json:"logSourceType"`
+	LogEventPhase  map[string]int  `json:"logEventPhase"`
+	TimeTickOffset json.RawMessage `json:"timeTickOffset"` // 可能是字符串或数字
+}
+
+// NetLog 事件
+type netLogEvent struct {
+	Phase  int `json:"phase"`
+	Source struct {
+		ID   int `json:"id"`
+		Type int `json:"type"`
+	} `json:"source"`
+	Time   json.RawMessage `json:"time"`
+	Type   int             `json:"type"`
+	Params struct {
+		URL       string   `json:"url"`
+		Method    string   `json:"method"`
+		Line      string   `json:"line"`
+		Headers   []string `json:"headers"`
+		Bytes     string   `json:"bytes"`
+		ByteCount int64    `json:"byte_count"`
+		NetError  int      `json:"net_error"`
+	} `json:"params"`
+}
+
+// 正在转换的请求，一个 URL_REQUEST 重定向时会产生多个条目
+type netLogRequest struct {
+	entry    Entry
+	seq      int // 开始的顺序
+	started  time.Time
+	headers  time.Time // 收到响应头的时间
+	finished time.Time
+	body     []byte
+	size     int64
+}
+
+// 文件开头为包含 constants 的 JSON 对象
+func sniffNetLog(head []byte) bool {
+	trimmed := bytes.TrimLeft(head, " \t\r\n\ufeff")
+	return bytes.HasPrefix(trimmed, []byte("{")) && bytes.Contains(head, []byte(`"logEventTypes"`))
+}
+
+// 解析 NetLog 中以字符串或数字表示的毫秒数
+func netLogMillis(raw json.RawMessage) float64 {
+	value, _ := strconv.ParseFloat(strings.Trim(string(raw), `"`), 64)
+	return value
+}
+
+// 转换 Chrome NetLog 文件，请求结束时输出条目，文件被截断时保留已读取的事件
+func convertNetLog(r io.Reader, emit func(*Entry) error) error {
+	decoder := json.NewDecoder(r)
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+
+	var constants netLogConstants
+	var eventNames map[int]string
+	var urlRequestType, phaseEnd int
+	var tickOffset float64
+	requests := make(map[int]*netLogRequest)
+	started := 0
+	finish := func(request *netLogRequest, at time.Time) error {
+		request.finished = at
+		return emit(finishNetLog(request))
+	}
+
+	for decoder.More() {
+		key, err := readKey(decoder)
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "constants":
+			if err := decoder.Decode(&constants); err != nil {
+				return err
+			}
+			eventNames = make(map[int]string, len(constants.LogEventTypes))
+			for name, id := range constants.LogEventTypes {
+				eventNames[id] = name
+			}
+			urlRequestType = constants.LogSourceType["URL_REQUEST"]
+			phaseEnd = 2
+			if id, ok := constants.LogEventPhase["PHASE_END"]; ok {
+				phaseEnd = id
+			}
+			tickOffset = netLogMillis(constants.TimeTickOffset)
+		case "events":
+			if eventNames == nil {
+				return fmt.Errorf("NetLog 格式错误: events 之前缺少 constants")
+			}
+			if err := expectDelim(decoder, '['); err != nil {
+				return err
+			}
+			for decoder.More() {
+				var event netLogEvent
+				if err := decoder.Decode(&event); err != nil {
+					// Chrome 未正常结束记录时文件会被截断
+					break
+				}
+				if event.Source.Type != urlRequestType {
+					continue
+				}
+				at := time.UnixMicro(int64((tickOffset + netLogMillis(event.Time)) * 1000))
+				name := eventNames[event.Type]
+				request := requests[event.Source.ID]
+				if name == "URL_REQUEST_START_JOB" && event.Phase != phaseEnd {
+					// 重定向时同一个请求会再次开始
+					if request != nil {
+						if err := finish(request, at); err != nil {
+							return err
+						}
+					}
+					started++
+					requests[event.Source.ID] = &netLogRequest{
+						entry:   newImportedEntry(event.Params.Method, event.Params.URL, "", at),
+						seq:     started,
+						started: at,
+					}
+					continue
+				}
+				if request == nil {
+					continue
+				}
+				switch {
+				case strings.HasPrefix(name, "HTTP_TRANSACTION_") && strings.HasSuffix(name, "SEND_REQUEST_HEADERS"):
+					netLogRequestHeaders(&request.entry, event.Params.Line, event.Params.Headers)
+				case name == "HTTP_TRANSACTION_READ_RESPONSE_HEADERS":
+					request.headers = at
+					netLogResponseHeaders(&request.entry, event.Params.Headers)
+				case name == "URL_REQUEST_JOB_FILTERED_BYTES_READ":
+					request.size += event.Params.ByteCount
+					if data, err := base64.StdEncoding.DecodeString(event.Params.Bytes); err == nil {
+						request.body = append(request.body, data...)
+					}
+				case name == "REQUEST_ALIVE" && event.Phase == phaseEnd:
+					if event.Params.NetError != 0 {
+						request.entry.Comment = fmt.Sprintf("net_error %d", event.Params.NetError)
+					}
+					delete(requests, event.Source.ID)
+					if err := finish(request, at); err != nil {
+						return err
+					}
+				}
+			}
+			// 未结束的请求按开始的顺序输出
+			for _, request := range netLogPending(requests) {
+				if err := emit(finishNetLog(request)); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 未结束的请求，按开始的顺序排列
+func netLogPending(requests map[int]*netLogRequest) []*netLogRequest {
+	pending := make([]*netLogRequest, 0, len(requests))
+	for _, request := range requests {
+		pending = append(pending, request)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].seq < pending[j].seq })
+	return pending
+}
+
+// 请求头为 "名称: 值" 形式，HTTP/1 的请求行单独记录在 line 中
+func netLogRequestHeaders(entry *Entry, line string, headers []string) {
+	if fields := strings.Fields(line); len(fields) == 3 {
+		entry.Request.HTTPVersion = fields[2]
+	}
+	entry.Request.Headers = []Header{}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ": ")
+		if !ok {
+			continue
+		}
+		// HTTP/2 和 QUIC 的伪头部
+		if strings.HasPrefix(name, ":") {
+			if entry.Request.HTTPVersion == "" {
+				entry.Request.HTTPVersion = "HTTP/2"
+			}
+			continue
+		}
+		entry.Request.Headers = append(entry.Request.Headers, Header{Name: name, Value: value})
+	}
+	entry.Request.HeadersSize = -1
+}
+
+// 响应头的第一项为状态行
+func netLogResponseHeaders(entry *Entry, headers []string) {
+	entry.Response.Headers = []Header{}
+	for i, header := range headers {
+		if i == 0 && strings.HasPrefix(header, "HTTP/") {
+			fields := strings.SplitN(header, " ", 3)
+			entry.Response.HTTPVersion = fields[0]
+			if len(fields) > 1 {
+				entry.Response.Status, _ = strconv.Atoi(fields[1])
+			}
+			if len(fields) > 2 {
+				entry.Response.StatusText = fields[2]
+			}
+			continue
+		}
+		if name, value, ok := strings.Cut(header, ": "); ok {
+			entry.Response.Headers = append(entry.Response.Headers, Header{Name: name, Value: value})
+		}
+	}
+	entry.Response.RedirectURL = headerValue(entry.Response.Headers, "Location")
+}
+
+// 计算耗时并生成响应内容
+func finishNetLog(request *netLogRequest) *Entry {
+	entry := &request.entry
+	entry.Response.Content = importedContent(request.body, headerValue(entry.Response.Headers, "Content-Type"))
+	if len(request.body) == 0 {
+		// 未记录响应内容时只有大小
+		entry.Response.Content.Size = request.size
+	}
+	entry.Response.BodySize = request.size
+	if request.headers.IsZero() {
+		request.headers = request.finished
+	}
+	entry.Timings.Send = 0
+	entry.Timings.Wait = max(millisBetween(request.started, request.headers), 0)
+	entry.Timings.Receive = max(millisBetween(request.headers, request.finished), 0)
+	entry.Time = max(millisBetween(request.started, request.finished), 0)
+	return entry
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 超过该大小的请求体和响应体不作为示例
+const maxExampleSize = 64 << 10
+
+var (
+	uuidPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hashPattern  = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	tokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{20,}$`)
+)
+
+// 路径模板中的参数
+type pathParam struct {
+	Name    string
+	Kind    string // integer、uuid、hash 或 token
+	Example string
+}
+
+// 判断路径段是否为ID，返回ID的种类，普通路径段返回空字符串
+func identifierKind(segment string) string {
+	switch {
+	case segment == "":
+		return ""
+	case strings.Trim(segment, "0123456789") == "":
+		return "integer"
+	case uuidPattern.MatchString(segment):
+		return "uuid"
+	case hashPattern.MatchString(segment) && strings.ContainsAny(segment, "0123456789"):
+		return "hash"
+	case tokenPattern.MatchString(segment) && strings.ContainsAny(segment, "0123456789") && strings.IndexFunc(segment, unicode.IsLetter) >= 0:
+		return "token"
+	}
+	return ""
+}
+
+// 根据前一个路径段生成参数名，如 users/123 为 userId
+func pathParamName(previous string, used map[string]bool) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range previous {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = b.Len() > 0
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		} else if b.Len() == 0 {
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	name := b.String()
+	if strings.HasSuffix(name, "ies") && len(name) > 4 {
+		name = strings.TrimSuffix(name, "ies") + "y"
+	} else if strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") && len(name) > 3 {
+		name = strings.TrimSuffix(name, "s")
+	}
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "id"
+	} else {
+		name += "Id"
+	}
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
+
+// 归一化路径，将数字、UUID、哈希等ID路径段替换为 replace 的返回值
+// replace 的参数为ID前一个普通路径段（解码后）、ID的种类和ID的值，接口统计、对比和 OpenAPI 导出共用
+func normalizePath(escapedPath string, replace func(previous, kind, value string) string) string {
+	segments := strings.Split(escapedPath, "/")
+	previous := ""
+	for i, segment := range segments {
+		value := segment
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			value = unescaped
+		}
+		kind := identifierKind(value)
+		if kind == "" {
+			if segment != "" {
+				previous = value
+			}
+			continue
+		}
+		segments[i] = replace(previous, kind, value)
+		previous = ""
+	}
+	template := strings.Join(segments, "/")
+	if template == "" {
+		template = "/"
+	}
+	return template
+}
+
+// 推断路径模板，将数字、UUID、哈希等ID替换为参数，如 /users/123/orders 为 /users/{userId}/orders
+func pathTemplate(escapedPath string) (string, []pathParam) {
+	var params []pathParam
+	used := make(map[string]bool)
+	template := normalizePath(escapedPath, func(previous, kind, value string) string {
+		param := pathParam{Name: pathParamName(previous, used), Kind: kind, Example: value}
+		params = append(params, param)
+		return "{" + param.Name + "}"
+	})
+	return template, params
+}
+
+// 根据示例推断 JSON Schema
+func inferSchema(value any) map[string]any {
+	switch v := value.(type) {
+	case map[string]any:
+		properties := make(map[string]any, len(v))
+		for key, item := range v {
+			properties[key] = inferSchema(item)
+		}
+		return map[string]any{"type": "object", "properties": properties}
+	case []any:
+		schema := map[string]any{"type": "array", "items": map[string]any{}}
+		if len(v) > 0 {
+			schema["items"] = inferSchema(v[0])
+		}
+		return schema
+	case string:
+		return map[string]any{"type": "string"}
+	case float64:
+		if v == float64(int64(v)) {
+			return map[string]any{"type": "integer"}
+		}
+		return map[string]any{"type": "number"}
+	case bool:
+		return map[string]any{"type": "boolean"}
+	}
+	return map[string]any{"nullable": true}
+}
+
+// 路径参数的 Schema
+func pathParamSchema(kind string) map[string]any {
+	switch kind {
+	case "integer":
+		return map[string]any{"type": "integer"}
+	case "uuid":
+		return map[string]any{"type": "string", "format": "uuid"}
+	}
+	return map[string]any{"type": "string"}
+}
+
+type openAPIDocument struct {
+	OpenAPI string                      `json:"openapi"`
+	Info    openAPIInfo                 `json:"info"`
+	Servers []openAPIServer             `json:"servers,omitempty"`
+	Paths   map[string]*openAPIPathItem `json:"paths"`
+}
+
+// 路径下的操作，按小写的请求方法索引
+// 录制了多个域名时 Servers 列出该路径出现过的地址，覆盖文档级的 servers
+type openAPIPathItem struct {
+	Servers    []openAPIServer
+	Operations map[string]*openAPIOperation
+}
+
+func (p *openAPIPathItem) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]any, len(p.Operations)+1)
+	for method, operation := range p.Operations {
+		fields[method] = operation
+	}
+	if len(p.Servers) > 0 {
+		fields["servers"] = p.Servers
+	}
+	return json.Marshal(fields)
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIOperation struct {
+	Summary     string                      `json:"summary"`
+	OperationID string                      `json:"operationId"`
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   map[string]any `json:"schema"`
+	Example  any            `json:"example,omitempty"`
+}
+
+type openAPIRequestBody struct {
+	Content map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema  map[string]any `json:"schema"`
+	Example any            `json:"example,omitempty"`
+}
+
+// 根据请求体或响应体生成媒体类型，JSON 内容推断 Schema，其他文本直接作为示例
+func newOpenAPIMediaType(mimeType string, data []byte) openAPIMediaType {
+	media := openAPIMediaType{Schema: map[string]any{"type": "string"}}
+	if len(data) == 0 || len(data) > maxExampleSize {
+		return media
+	}
+	if strings.Contains(mimeType, "json") {
+		var value any
+		if json.Unmarshal(data, &value) == nil {
+			return openAPIMediaType{Schema: inferSchema(value), Example: value}
+		}
+	}
+	if isPrintableText(data) {
+		media.Example = string(data)
+	} else {
+		media.Schema["format"] = "binary"
+	}
+	return media
+}
+
+// 表单请求体按字段生成对象 Schema，文件字段为 binary
+func newOpenAPIFormMediaType(postData *PostData) (openAPIMediaType, bool) {
+	properties := make(map[string]any)
+	example := make(map[string]any)
+	switch baseMimeType(postData.MimeType) {
+	case "application/x-www-form-urlencoded":
+		for _, field := range parseFormFields(postData.Text) {
+			properties[field.Name] = map[string]any{"type": "string"}
+			example[field.Name] = field.Value
+		}
+	case "multipart/form-data":
+		parts, err := parseMultipartParts(postData.MimeType, postData.Text)
+		if err != nil {
+			return openAPIMediaType{}, false
+		}
+		for _, part := range parts {
+			if part.FileName != "" || part.Binary {
+				properties[part.Name] = map[string]any{"type": "string", "format": "binary"}
+				continue
+			}
+			properties[part.Name] = map[string]any{"type": "string"}
+			example[part.Name] = part.Value
+		}
+	default:
+		return openAPIMediaType{}, false
+	}
+	media := openAPIMediaType{Schema: map[string]any{"type": "object", "properties": properties}}
+	if len(example) > 0 {
+		media.Example = example
+	}
+	return media, true
+}
+
+// 生成 operationId，如 GET /users/{userId} 为 getUsersUserId
+func operationID(method, template string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	upper := true
+	for _, r := range template {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// 根据录制的请求推断 OpenAPI 3 文档，按方法和路径模板分组，每个状态码保留第一个示例
+// 条目通过 read 逐个获取，只保留示例，不同时将所有请求体和响应体读入内存
+func buildOpenAPI(name string, count int, read func(i int) (*Entry, error)) (*openAPIDocument, error) {
+	doc := &openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: name, Description: "由 HAR Viewer 根据 " + name + " 中录制的请求推断", Version: "1.0.0"},
+		Paths:   make(map[string]*openAPIPathItem),
+	}
+	servers := make(map[string]bool)
+	pathServers := make(map[*openAPIPathItem]map[string]bool)
+	for i := 0; i < count; i++ {
+		entry, err := read(i)
+		if err != nil {
+			return nil, err
+		}
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		origin := u.Scheme + "://" + u.Host
+		if !servers[origin] {
+			servers[origin] = true
+			doc.Servers = append(doc.Servers, openAPIServer{URL: origin})
+		}
+
+		template, params := pathTemplate(u.EscapedPath())
+		method := strings.ToLower(entry.Request.Method)
+		pathItem := doc.Paths[template]
+		if pathItem == nil {
+			pathItem = &openAPIPathItem{Operations: make(map[string]*openAPIOperation)}
+			doc.Paths[template] = pathItem
+			pathServers[pathItem] = make(map[string]bool)
+		}
+		if !pathServers[pathItem][origin] {
+			pathServers[pathItem][origin] = true
+			pathItem.Servers = append(pathItem.Servers, openAPIServer{URL: origin})
+		}
+		operation := pathItem.Operations[method]
+		if operation == nil {
+			operation = &openAPIOperation{
+				Summary:     entry.Request.Method + " " + template,
+				OperationID: operationID(method, template),
+				Responses:   make(map[string]*openAPIResponse),
+			}
+			for _, param := range params {
+				var example any = param.Example
+				if n, err := strconv.ParseInt(param.Example, 10, 64); err == nil && param.Kind == "integer" {
+					example = n
+				}
+				operation.Parameters = append(operation.Parameters, openAPIParameter{
+					Name: param.Name, In: "path", Required: true, Schema: pathParamSchema(param.Kind), Example: example,
+				})
+			}
+			pathItem.Operations[method] = operation
+		}
+
+		// 合并各次请求出现的查询参数
+		for _, field := range parseFormFields(u.RawQuery) {
+			found := false
+			for _, param := range operation.Parameters {
+				if param.In == "query" && param.Name == field.Name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				operation.Parameters = append(operation.Parameters, openAPIParameter{
+					Name: field.Name, In: "query", Schema: map[string]any{"type": "string"}, Example: field.Value,
+				})
+			}
+		}
+
+		if postData := entry.Request.PostData; postData != nil && postData.Text != "" && operation.RequestBody == nil {
+			mimeType := baseMimeType(postData.MimeType)
+			if mimeType == "" {
+				mimeType = "application/octet-stream"
+			}
+			media, ok := newOpenAPIFormMediaType(postData)
+			if !ok {
+				media = newOpenAPIMediaType(mimeType, []byte(postData.Text))
+			}
+			operation.RequestBody = &openAPIRequestBody{Content: map[string]openAPIMediaType{mimeType: media}}
+		}
+
+		status := strconv.Itoa(entry.Response.Status)
+		if entry.Response.Status == 0 || operation.Responses[status] != nil {
+			continue
+		}
+		response := &openAPIResponse{Description: entry.Response.StatusText}
+		if response.Description == "" {
+			response.Description = status
+		}
+		if data, _, err := decodeContent(entry.Response.Content, entry.Response.Headers); err == nil && len(data) > 0 {
+			mimeType := baseMimeType(entry.Response.Content.MimeType)
+			if mimeType == "" {
+				mimeType = "application/octet-stream"
+			}
+			response.Content = map[string]openAPIMediaType{mimeType: newOpenAPIMediaType(mimeType, data)}
+		}
+		operation.Responses[status] = response
+	}
+
+	for _, pathItem := range doc.Paths {
+		// 只有一个域名时使用文档级的 servers
+		if len(doc.Servers) < 2 {
+			pathItem.Servers = nil
+		}
+		// 没有响应的操作也需要 responses 字段
+		for _, operation := range pathItem.Operations {
+			if len(operation.Responses) == 0 {
+				operation.Responses["default"] = &openAPIResponse{Description: "未录制到响应"}
+			}
+		}
+	}
+	return doc, nil
+}
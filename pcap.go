@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pcap 文件头的魔数（小端读取）
+const (
+	pcapMagicMicro   = 0xa1b2c3d4
+	pcapMagicNano    = 0xa1b23c4d
+	pcapMagicMicroBE = 0xd4c3b2a1
+	pcapMagicNanoBE  = 0x4d3cb2a1
+	pcapngMagic      = 0x0a0d0d0a
+)
+
+// 链路层类型
+const (
+	linkTypeNull     = 0
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeLinuxSLL = 113
+	linkTypeLoop     = 108
+	linkTypeSLL2     = 276
+)
+
+// 单个数据包的最大长度
+const maxPcapPacketSize = 1 << 20
+
+// TCP 数据段
+type tcpSegment struct {
+	seq  uint32
+	data []byte
+	at   time.Time
+}
+
+// TCP 连接的一个方向
+type tcpFlow struct {
+	base     uint32 // 起始序号，看到 SYN 时为 SYN 的序号加一
+	hasBase  bool
+	fin      bool
+	segments []tcpSegment
+}
+
+// 重组后的数据以及每段数据开始的位置和时间
+type tcpStream struct {
+	data    []byte
+	offsets []int
+	times   []time.Time
+}
+
+// TCP 连接，按首次出现的方向区分两端
+type tcpConn struct {
+	seq     int       // 首次出现的顺序
+	syn     time.Time // 客户端 SYN 的时间
+	synAck  time.Time // 服务端 SYN-ACK 的时间
+	synFrom string
+	flows   map[string]*tcpFlow
+	addrs   map[string]string // 源地址 -> 目标地址
+}
+
+// pcap 与 pcapng 文件开头的魔数
+func sniffPcap(head []byte) bool {
+	if len(head) < 4 {
+		return false
+	}
+	switch binary.LittleEndian.Uint32(head) {
+	case pcapMagicMicro, pcapMagicNano, pcapMagicMicroBE, pcapMagicNanoBE, pcapngMagic:
+		return true
+	}
+	return false
+}
+
+// 获取数据包中的 IP 数据
+func pcapIPPayload(linkType uint32, packet []byte) []byte {
+	switch linkType {
+	case linkTypeEthernet:
+		if len(packet) < 14 {
+			return nil
+		}
+		etherType, offset := binary.BigEndian.Uint16(packet[12:]), 14
+		// 802.1Q VLAN 标签
+		for (etherType == 0x8100 || etherType == 0x88a8) && len(packet) >= offset+4 {
+			etherType, offset = binary.BigEndian.Uint16(packet[offset+2:]), offset+4
+		}
+		if etherType != 0x0800 && etherType != 0x86dd {
+			return nil
+		}
+		return packet[offset:]
+	case linkTypeNull, linkTypeLoop:
+		if len(packet) < 4 {
+			return nil
+		}
+		return packet[4:]
+	case linkTypeRaw:
+		return packet
+	case linkTypeLinuxSLL:
+		if len(packet) < 16 {
+			return nil
+		}
+		return packet[16:]
+	case linkTypeSLL2:
+		if len(packet) < 20 {
+			return nil
+		}
+		return packet[20:]
+	}
+	return nil
+}
+
+// 解析 IPv4/IPv6 和 TCP 头部，返回源地址、目标地址、TCP 头部和数据
+func pcapTCP(ip []byte) (src, dst string, tcp []byte, ok bool) {
+	if len(ip) < 1 {
+		return
+	}
+	switch ip[0] >> 4 {
+	case 4:
+		headerLen := int(ip[0]&0x0f) * 4
+		if len(ip) < 20 || headerLen < 20 || len(ip) < headerLen || ip[9] != 6 {
+			return
+		}
+		// 不处理分片
+		if binary.BigEndian.Uint16(ip[6:])&0x3fff != 0 {
+			return
+		}
+		total := int(binary.BigEndian.Uint16(ip[2:]))
+		if total >= headerLen && total < len(ip) {
+			ip = ip[:total] // 去掉以太网的填充
+		}
+		src, dst = net.IP(ip[12:16]).String(), net.IP(ip[16:20]).String()
+		tcp = ip[headerLen:]
+	case 6:
+		if len(ip) < 40 || ip[6] != 6 {
+			return
+		}
+		total := 40 + int(binary.BigEndian.Uint16(ip[4:]))
+		if total < len(ip) {
+			ip = ip[:total]
+		}
+		src, dst = net.IP(ip[8:24]).String(), net.IP(ip[24:40]).String()
+		tcp = ip[40:]
+	default:
+		return
+	}
+	return src, dst, tcp, len(tcp) >= 20
+}
+
+// 读取 pcap 文件中的 TCP 连接，连接关闭时交给 done 处理，文件结束时按出现的顺序处理其余连接
+func readPcapConns(r io.Reader, done func(*tcpConn) error) error {
+	reader := bufio.NewReader(r)
+	if magic, _ := reader.Peek(4); len(magic) == 4 && binary.LittleEndian.Uint32(magic) == pcapngMagic {
+		return errors.New("暂不支持 pcapng 格式，请在 Wireshark 中另存为 pcap 格式")
+	}
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return fmt.Errorf("读取文件头失败: %v", err)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	nano := false
+	switch binary.LittleEndian.Uint32(header) {
+	case pcapMagicMicro:
+	case pcapMagicNano:
+		nano = true
+	case pcapMagicMicroBE:
+		order = binary.BigEndian
+	case pcapMagicNanoBE:
+		order, nano = binary.BigEndian, true
+	default:
+		return errors.New("不是有效的 pcap 文件")
+	}
+	linkType := order.Uint32(header[20:]) & 0x0fffffff
+
+	conns := make(map[string]*tcpConn)
+	closed := make(map[string]bool) // 已关闭的连接，忽略之后的重传和 ACK
+	started := 0
+	record := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(reader, record); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break // 抓包中断时最后一个数据包可能不完整
+			}
+			return err
+		}
+		seconds, fraction, length := order.Uint32(record), order.Uint32(record[4:]), order.Uint32(record[8:])
+		if length > maxPcapPacketSize {
+			return fmt.Errorf("数据包长度无效: %d", length)
+		}
+		packet := make([]byte, length)
+		if _, err := io.ReadFull(reader, packet); err != nil {
+			break
+		}
+		at := time.Unix(int64(seconds), int64(fraction)*1000)
+		if nano {
+			at = time.Unix(int64(seconds), int64(fraction))
+		}
+
+		src, dst, tcp, ok := pcapTCP(pcapIPPayload(linkType, packet))
+		if !ok {
+			continue
+		}
+		from := net.JoinHostPort(src, strconv.Itoa(int(binary.BigEndian.Uint16(tcp[0:]))))
+		to := net.JoinHostPort(dst, strconv.Itoa(int(binary.BigEndian.Uint16(tcp[2:]))))
+		seq := binary.BigEndian.Uint32(tcp[4:])
+		dataOffset := int(tcp[12]>>4) * 4
+		flags := tcp[13]
+		if dataOffset < 20 || dataOffset > len(tcp) {
+			continue
+		}
+
+		key := from + " " + to
+		if from > to {
+			key = to + " " + from
+		}
+		syn, ack := flags&0x02 != 0, flags&0x10 != 0
+		fin, rst := flags&0x01 != 0, flags&0x04 != 0
+		payload := tcp[dataOffset:]
+		conn := conns[key]
+		if conn != nil && syn && !ack && conn.flows[from] != nil && len(conn.flows[from].segments) > 0 {
+			// 复用同一端口重新发起的连接
+			delete(conns, key)
+			if err := done(conn); err != nil {
+				return err
+			}
+			conn = nil
+		}
+		if conn == nil {
+			if syn {
+				delete(closed, key)
+			} else if closed[key] || len(payload) == 0 {
+				continue
+			}
+			started++
+			conn = &tcpConn{seq: started, flows: map[string]*tcpFlow{}, addrs: map[string]string{}}
+			conns[key] = conn
+		}
+		flow := conn.flows[from]
+		if flow == nil {
+			flow = &tcpFlow{}
+			conn.flows[from] = flow
+			conn.addrs[from] = to
+		}
+		if syn {
+			flow.base, flow.hasBase = seq+1, true
+			if !ack {
+				conn.syn, conn.synFrom = at, from
+			} else {
+				conn.synAck = at
+			}
+			continue
+		}
+		if len(payload) > 0 {
+			if !flow.hasBase {
+				flow.base, flow.hasBase = seq, true
+			}
+			flow.segments = append(flow.segments, tcpSegment{seq: seq, data: payload, at: at})
+		}
+		flow.fin = flow.fin || fin
+		// 双方都发送 FIN 或收到 RST 时连接结束
+		if rst || conn.closed() {
+			delete(conns, key)
+			closed[key] = true
+			if err := done(conn); err != nil {
+				return err
+			}
+		}
+	}
+
+	pending := make([]*tcpConn, 0, len(conns))
+	for _, conn := range conns {
+		pending = append(pending, conn)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].seq < pending[j].seq })
+	for _, conn := range pending {
+		if err := done(conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 双方是否都已发送 FIN
+func (c *tcpConn) closed() bool {
+	if len(c.flows) < 2 {
+		return false
+	}
+	for _, flow := range c.flows {
+		if !flow.fin {
+			return false
+		}
+	}
+	return true
+}
+
+// 按序号重组数据，去掉重传的部分，遇到缺失的数据时停止
+func (f *tcpFlow) reassemble() tcpStream {
+	segments := f.segments
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].seq-f.base < segments[j].seq-f.base
+	})
+	var stream tcpStream
+	for _, segment := range segments {
+		offset := int(segment.seq - f.base)
+		end := offset + len(segment.data)
+		if offset > len(stream.data) {
+			break
+		}
+		if end <= len(stream.data) {
+			continue
+		}
+		stream.offsets = append(stream.offsets, len(stream.data))
+		stream.times = append(stream.times, segment.at)
+		stream.data = append(stream.data, segment.data[len(stream.data)-offset:]...)
+	}
+	return stream
+}
+
+// 获取某个位置的数据到达的时间
+func (s *tcpStream) timeAt(offset int) time.Time {
+	i := sort.SearchInts(s.offsets, offset+1) - 1
+	if i < 0 {
+		return time.Time{}
+	}
+	return s.times[i]
+}
+
+// 判断数据是否以HTTP请求行开头
+func isHTTPRequestStart(data []byte) bool {
+	line, _, _ := bytes.Cut(data[:min(len(data), 2048)], []byte("\r\n"))
+	method, rest, ok := bytes.Cut(line, []byte(" "))
+	if !ok || len(method) == 0 || len(method) > 16 {
+		return false
+	}
+	for _, c := range method {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return bytes.Contains(rest, []byte(" HTTP/1."))
+}
+
+// 已读取的字节数
+func consumed(data []byte, reader *bytes.Reader, buffered *bufio.Reader) int {
+	return len(data) - reader.Len() - buffered.Buffered()
+}
+
+// 转换 pcap 文件，只能还原未加密的 HTTP/1.x 请求，连接关闭时输出其中的条目
+func convertPcap(r io.Reader, emit func(*Entry) error) error {
+	return readPcapConns(r, func(conn *tcpConn) error {
+		entries := pcapConnEntries(conn)
+		for i := range entries {
+			if err := emit(&entries[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// 还原一个连接中的请求
+func pcapConnEntries(conn *tcpConn) []Entry {
+	// 以HTTP请求行开头的方向为客户端
+	var client, server tcpStream
+	var clientAddr, serverAddr string
+	found := false
+	for from, flow := range conn.flows {
+		stream := flow.reassemble()
+		if !isHTTPRequestStart(stream.data) {
+			continue
+		}
+		client, clientAddr, serverAddr, found = stream, from, conn.addrs[from], true
+		if peer := conn.flows[serverAddr]; peer != nil {
+			server = peer.reassemble()
+		}
+		break
+	}
+	if !found {
+		return nil
+	}
+
+	requestReader := bytes.NewReader(client.data)
+	requests := bufio.NewReader(requestReader)
+	responseReader := bytes.NewReader(server.data)
+	responses := bufio.NewReader(responseReader)
+	serverHost, _, _ := net.SplitHostPort(serverAddr)
+	_, clientPort, _ := net.SplitHostPort(clientAddr)
+	var entries []Entry
+	for first := true; ; first = false {
+		requestStart := consumed(client.data, requestReader, requests)
+		req, err := http.ReadRequest(requests)
+		if err != nil {
+			break
+		}
+		reqBody, _ := io.ReadAll(req.Body)
+		requestEnd := consumed(client.data, requestReader, requests) - 1
+		if req.Host == "" {
+			req.Host = serverAddr
+		}
+
+		var resp *http.Response
+		var respBody []byte
+		responseStart := consumed(server.data, responseReader, responses)
+		for {
+			resp, err = http.ReadResponse(responses, req)
+			if err != nil {
+				resp = nil
+				break
+			}
+			// 跳过 100 Continue 等临时响应
+			if resp.StatusCode >= 200 || resp.StatusCode == http.StatusSwitchingProtocols {
+				break
+			}
+			responseStart = consumed(server.data, responseReader, responses)
+		}
+		if resp != nil {
+			respBody, _ = io.ReadAll(resp.Body)
+		}
+		responseEnd := consumed(server.data, responseReader, responses) - 1
+
+		started := client.timeAt(requestStart)
+		requestDone := client.timeAt(requestEnd)
+		entry := entryFromHTTP(req, reqBody, resp, respBody, started)
+		entry.ServerIPAddress = serverHost
+		entry.Connection = clientPort
+		entry.Timings.Send = max(millisBetween(started, requestDone), 0)
+		finished := requestDone
+		if resp != nil {
+			firstByte := server.timeAt(responseStart)
+			finished = server.timeAt(responseEnd)
+			entry.Timings.Wait = max(millisBetween(requestDone, firstByte), 0)
+			entry.Timings.Receive = max(millisBetween(firstByte, finished), 0)
+		}
+		// 连接的第一个请求包含建立连接的时间
+		if first && conn.synFrom == clientAddr && !conn.synAck.IsZero() {
+			entry.Timings.Connect = max(millisBetween(conn.syn, conn.synAck), 0)
+			started = conn.syn
+			entry.StartedDateTime = started.Format(time.RFC3339Nano)
+		}
+		entry.Time = max(millisBetween(started, finished), 0)
+		entries = append(entries, entry)
+
+		if resp == nil || resp.StatusCode == http.StatusSwitchingProtocols || strings.EqualFold(req.Method, "CONNECT") {
+			break
+		}
+	}
+	return entries
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// 表单字段
+type formField struct {
+	Name  string
+	Value string
+}
+
+// multipart 中的一个分段
+type multipartPart struct {
+	Name        string
+	FileName    string
+	ContentType string
+	Headers     []Header
+	Value       string
+	SizeText    string
+	Binary      bool
+}
+
+// 请求体视图数据
+type postDataView struct {
+	MimeType   string
+	SizeText   string
+	Form       []formField
+	Parts      []multipartPart
+	JSON       string
+	Raw        string
+	DefaultTab string
+	Error      string
+	Empty      bool
+}
+
+// 按原始顺序解析 urlencoded 表单
+func parseFormFields(text string) []formField {
+	var fields []formField
+	for _, pair := range strings.Split(text, "&") {
+		if pair == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(value); err == nil {
+			value = unescaped
+		}
+		fields = append(fields, formField{Name: name, Value: value})
+	}
+	return fields
+}
+
+// 解析 multipart 请求体
+func parseMultipartParts(mimeType, text string) ([]multipartPart, error) {
+	_, params, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return nil, err
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, fmt.Errorf("缺少 multipart 分隔符")
+	}
+
+	var parts []multipartPart
+	reader := multipart.NewReader(strings.NewReader(text), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return parts, err
+		}
+
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return parts, err
+		}
+
+		item := multipartPart{
+			Name:        part.FormName(),
+			FileName:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			SizeText:    formatFileSize(len(data)),
+		}
+		names := make([]string, 0, len(part.Header))
+		for name := range part.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, value := range part.Header[name] {
+				item.Headers = append(item.Headers, Header{Name: name, Value: value})
+			}
+		}
+		if isPrintableText(data) {
+			item.Value = string(data)
+		} else {
+			item.Binary = true
+		}
+		parts = append(parts, item)
+	}
+	return parts, nil
+}
+
+// 构建请求体视图
+func buildPostDataView(postData *PostData) postDataView {
+	if postData == nil || (postData.Text == "" && len(postData.Params) == 0) {
+		return postDataView{Empty: true}
+	}
+
+	view := postDataView{
+		MimeType: postData.MimeType,
+		SizeText: formatFileSize(len(postData.Text)),
+		Raw:      postData.Text,
+	}
+	mimeType := baseMimeType(postData.MimeType)
+
+	switch {
+	case mimeType == "application/x-www-form-urlencoded":
+		if postData.Text != "" {
+			view.Form = parseFormFields(postData.Text)
+		}
+	case strings.HasPrefix(mimeType, "multipart/"):
+		if postData.Text != "" {
+			parts, err := parseMultipartParts(postData.MimeType, postData.Text)
+			if err != nil {
+				view.Error = fmt.Sprintf("解析 multipart 失败: %v", err)
+			}
+			view.Parts = parts
+		}
+	}
+
+	// 没有原始文本时使用HAR中已解析的参数
+	if len(view.Form) == 0 && len(view.Parts) == 0 {
+		for _, param := range postData.Params {
+			if param.FileName != "" || param.ContentType != "" {
+				view.Parts = append(view.Parts, multipartPart{
+					Name:        param.Name,
+					FileName:    param.FileName,
+					ContentType: param.ContentType,
+					Value:       param.Value,
+					SizeText:    formatFileSize(len(param.Value)),
+				})
+			} else {
+				view.Form = append(view.Form, formField{Name: param.Name, Value: param.Value})
+			}
+		}
+	}
+
+	trimmed := strings.TrimSpace(postData.Text)
+	if strings.Contains(mimeType, "json") || strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, []byte(trimmed), "", "  "); err == nil {
+			view.JSON = buf.String()
+		}
+	}
+
+	switch {
+	case len(view.Form) > 0:
+		view.DefaultTab = "form"
+	case len(view.Parts) > 0:
+		view.DefaultTab = "multipart"
+	case view.JSON != "":
+		view.DefaultTab = "json"
+	default:
+		view.DefaultTab = "raw"
+	}
+	return view
+}
+
+var requestBodyTemplate = `<div class="body-view">
+    {{if .Empty}}
+    <p>无请求体内容</p>
+    {{else}}
+    <p class="body-meta">类型: {{.MimeType}} | 大小: {{.SizeText}}</p>
+    {{if .Error}}<p class="error-message">{{.Error}}</p>{{end}}
+    <div class="body-tabs">
+        {{if .Form}}<button type="button" class="btn body-tab{{if eq .DefaultTab "form"}} active{{end}}" onclick="switchBodyTab(this, 'form')">表单</button>{{end}}
+        {{if .Parts}}<button type="button" class="btn body-tab{{if eq .DefaultTab "multipart"}} active{{end}}" onclick="switchBodyTab(this, 'multipart')">Multipart</button>{{end}}
+        {{if .JSON}}<button type="button" class="btn body-tab{{if eq .DefaultTab "json"}} active{{end}}" onclick="switchBodyTab(this, 'json')">JSON</button>{{end}}
+        <button type="button" class="btn body-tab{{if eq .DefaultTab "raw"}} active{{end}}" onclick="switchBodyTab(this, 'raw')">原始</button>
+    </div>
+    {{if .Form}}
+    <div class="body-pane" data-tab="form"{{if ne .DefaultTab "form"}} style="display: none;"{{end}}>
+        <table class="param-table">
+            <tr><th>名称</th><th>值</th></tr>
+            {{range .Form}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>{{end}}
+        </table>
+    </div>
+    {{end}}
+    {{if .Parts}}
+    <div class="body-pane" data-tab="multipart"{{if ne .DefaultTab "multipart"}} style="display: none;"{{end}}>
+        {{range .Parts}}
+        <div class="multipart-part">
+            <p><strong>{{.Name}}</strong>{{if .FileName}} | 文件名: {{.FileName}}{{end}}{{if .ContentType}} | 类型: {{.ContentType}}{{end}} | 大小: {{.SizeText}}</p>
+            {{if .Headers}}<ul>{{range .Headers}}<li>{{.Name}}: {{.Value}}</li>{{end}}</ul>{{end}}
+            {{if .Binary}}<p>[二进制内容]</p>{{else if .Value}}<pre>{{.Value}}</pre>{{end}}
+        </div>
+        {{end}}
+    </div>
+    {{end}}
+    {{if .JSON}}<pre class="body-pane" data-tab="json"{{if ne .DefaultTab "json"}} style="display: none;"{{end}}>{{.JSON}}</pre>{{end}}
+    <pre class="body-pane" data-tab="raw"{{if ne .DefaultTab "raw"}} style="display: none;"{{end}}>{{.Raw}}</pre>
+    {{end}}
+</div>`
+
+// 请求体处理函数
+func requestBodyHandler(w http.ResponseWriter, r *http.Request) {
+	entry, ok := entryFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tmpl, err := template.New("request-body").Parse(requestBodyTemplate)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("解析模板失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	view := buildPostDataView(entry.Request.PostData)
+	tmpl.Execute(w, view)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Postman Collection v2.1
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+type postmanInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+// 集合中的一项，文件夹包含 Item，请求包含 Request
+type postmanItem struct {
+	Name     string            `json:"name"`
+	Item     []postmanItem     `json:"item,omitempty"`
+	Request  *postmanRequest   `json:"request,omitempty"`
+	Response []postmanResponse `json:"response,omitempty"`
+}
+
+type postmanKeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Type  string `json:"type,omitempty"` // formdata 中为 text 或 file
+	Src   string `json:"src,omitempty"`  // formdata 中的文件名
+}
+
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Protocol string            `json:"protocol,omitempty"`
+	Host     []string          `json:"host,omitempty"`
+	Port     string            `json:"port,omitempty"`
+	Path     []string          `json:"path,omitempty"`
+	Query    []postmanKeyValue `json:"query,omitempty"`
+}
+
+type postmanBody struct {
+	Mode       string            `json:"mode"`
+	Raw        string            `json:"raw,omitempty"`
+	URLEncoded []postmanKeyValue `json:"urlencoded,omitempty"`
+	FormData   []postmanKeyValue `json:"formdata,omitempty"`
+	Options    *postmanOptions   `json:"options,omitempty"`
+}
+
+type postmanOptions struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanKeyValue `json:"header"`
+	Body   *postmanBody      `json:"body,omitempty"`
+	URL    postmanURL        `json:"url"`
+}
+
+// 录制的响应，在 Postman 中显示为示例
+type postmanResponse struct {
+	Name            string            `json:"name"`
+	OriginalRequest *postmanRequest   `json:"originalRequest"`
+	Status          string            `json:"status"`
+	Code            int               `json:"code"`
+	Language        string            `json:"_postman_previewlanguage,omitempty"`
+	Header          []postmanKeyValue `json:"header"`
+	Body            string            `json:"body"`
+}
+
+// 根据 MIME 类型确定 Postman 的语言
+func postmanLanguage(mimeType string) string {
+	mimeType = baseMimeType(mimeType)
+	switch {
+	case strings.Contains(mimeType, "json"):
+		return "json"
+	case strings.Contains(mimeType, "html"):
+		return "html"
+	case strings.Contains(mimeType, "xml"):
+		return "xml"
+	case strings.Contains(mimeType, "javascript"):
+		return "javascript"
+	}
+	return "text"
+}
+
+// 拆分URL
+func newPostmanURL(rawURL string) postmanURL {
+	result := postmanURL{Raw: rawURL}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return result
+	}
+	result.Protocol = u.Scheme
+	if u.Hostname() != "" {
+		result.Host = strings.Split(u.Hostname(), ".")
+	}
+	result.Port = u.Port()
+	for _, segment := range strings.Split(strings.TrimPrefix(u.EscapedPath(), "/"), "/") {
+		if segment != "" {
+			result.Path = append(result.Path, segment)
+		}
+	}
+	for _, field := range parseFormFields(u.RawQuery) {
+		result.Query = append(result.Query, postmanKeyValue{Key: field.Name, Value: field.Value})
+	}
+	return result
+}
+
+// 转换请求，请求头和请求体的处理与代码片段相同
+func newPostmanRequest(req *Request) *postmanRequest {
+	s := newSnippetRequest(req)
+	result := &postmanRequest{Method: s.Method, Header: []postmanKeyValue{}, URL: newPostmanURL(s.URL)}
+	for _, header := range s.Headers {
+		result.Header = append(result.Header, postmanKeyValue{Key: header.Name, Value: header.Value})
+	}
+	if s.Body == "" {
+		return result
+	}
+
+	mimeType := headerValue(s.Headers, "Content-Type")
+	switch baseMimeType(mimeType) {
+	case "application/x-www-form-urlencoded":
+		result.Body = &postmanBody{Mode: "urlencoded"}
+		for _, field := range parseFormFields(s.Body) {
+			result.Body.URLEncoded = append(result.Body.URLEncoded, postmanKeyValue{Key: field.Name, Value: field.Value})
+		}
+		return result
+	case "multipart/form-data":
+		if parts, err := parseMultipartParts(mimeType, s.Body); err == nil {
+			// 分隔符由 Postman 重新生成
+			result.Header = removePostmanHeader(result.Header, "Content-Type")
+			result.Body = &postmanBody{Mode: "formdata"}
+			for _, part := range parts {
+				field := postmanKeyValue{Key: part.Name, Value: part.Value, Type: "text"}
+				if part.FileName != "" || part.Binary {
+					field = postmanKeyValue{Key: part.Name, Type: "file", Src: part.FileName}
+				}
+				result.Body.FormData = append(result.Body.FormData, field)
+			}
+			return result
+		}
+	}
+	result.Body = &postmanBody{Mode: "raw", Raw: s.Body, Options: &postmanOptions{}}
+	result.Body.Options.Raw.Language = postmanLanguage(mimeType)
+	return result
+}
+
+// 删除指定名称的请求头
+func removePostmanHeader(headers []postmanKeyValue, name string) []postmanKeyValue {
+	result := headers[:0]
+	for _, header := range headers {
+		if !strings.EqualFold(header.Key, name) {
+			result = append(result, header)
+		}
+	}
+	return result
+}
+
+// 转换单个条目，录制的响应作为示例
+func newPostmanItem(entry *Entry) postmanItem {
+	request := newPostmanRequest(&entry.Request)
+	itemName := request.Method + " " + entry.Request.URL
+	if u, err := url.Parse(entry.Request.URL); err == nil {
+		itemName = request.Method + " " + u.EscapedPath()
+	}
+
+	response := postmanResponse{
+		Name:            itemName,
+		OriginalRequest: request,
+		Status:          entry.Response.StatusText,
+		Code:            entry.Response.Status,
+		Language:        postmanLanguage(entry.Response.Content.MimeType),
+		Header:          []postmanKeyValue{},
+	}
+	for _, header := range entry.Response.Headers {
+		// 内容已解压，不再保留压缩相关的头部
+		if strings.EqualFold(header.Name, "Content-Encoding") || strings.EqualFold(header.Name, "Content-Length") {
+			continue
+		}
+		response.Header = append(response.Header, postmanKeyValue{Key: header.Name, Value: header.Value})
+	}
+	if data, _, err := decodeContent(entry.Response.Content, entry.Response.Headers); err == nil && isPrintableText(data) {
+		response.Body = string(data)
+	}
+	return postmanItem{Name: itemName, Request: request, Response: []postmanResponse{response}}
+}
+
+// 流式写出 Postman 集合，按域名分为文件夹
+// 先根据内存中的索引分组，再逐个读取完整条目写出，不同时保留所有请求体和响应体
+func writePostmanCollection(w io.Writer, file *harFile, indexes []int) error {
+	var domains []string
+	folders := make(map[string][]int)
+	for _, index := range indexes {
+		domain := extractDomain(file.HAR.Log.Entries[index].Request.URL)
+		if domain == "" {
+			domain = "(无域名)"
+		}
+		if _, ok := folders[domain]; !ok {
+			domains = append(domains, domain)
+		}
+		folders[domain] = append(folders[domain], index)
+	}
+
+	// 缩进与 writeJSON 输出的格式相同
+	bw := bufio.NewWriter(w)
+	info, err := json.MarshalIndent(postmanInfo{
+		Name: file.Name, Description: "由 HAR Viewer 从 " + file.Name + " 导出", Schema: postmanSchema,
+	}, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(bw, "{\n  \"info\": %s,\n  \"item\": [", info)
+	for i, domain := range domains {
+		if i > 0 {
+			bw.WriteString(",")
+		}
+		name, _ := json.Marshal(domain)
+		fmt.Fprintf(bw, "\n    {\n      \"name\": %s,\n      \"item\": [", name)
+		for j, index := range folders[domain] {
+			entry, err := file.Entry(index)
+			if err != nil {
+				return err
+			}
+			data, err := json.MarshalIndent(newPostmanItem(&entry), "        ", "  ")
+			if err != nil {
+				return err
+			}
+			if j > 0 {
+				bw.WriteString(",")
+			}
+			bw.WriteString("\n        ")
+			bw.Write(data)
+		}
+		bw.WriteString("\n      ]\n    }")
+	}
+	bw.WriteString("\n  ]\n}\n")
+	return bw.Flush()
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 重放响应体的读取上限
+const maxReplayBodySize = 10 << 20
+
+// 重放使用的HTTP客户端，不自动跟随重定向，便于和录制的响应对比
+var replayClient = &http.Client{
+	Timeout: 30 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// 对比显示的一侧响应
+type replayResponse struct {
+	URL        string
+	Status     int
+	StatusText string
+	Time       float64
+	Headers    []Header
+	Body       bodyView
+	Truncated  bool // 响应体超过 maxReplayBodySize，只读取了前一部分
+	Error      string
+}
+
+var replayTemplate = `<div class="replay-result">
+    {{range .}}
+    <div class="replay-column">
+        <h4>{{.Title}}</h4>
+        {{with .Response}}
+        <p class="body-meta">{{.URL}}</p>
+        {{if .Error}}
+        <p class="error-message">{{.Error}}</p>
+        {{else}}
+        <p><strong>状态:</strong> <span class="status-code status-{{.Status}}">{{.Status}} {{.StatusText}}</span></p>
+        <p><strong>耗时:</strong> {{printf "%.2f" .Time}} ms</p>
+        {{if .Truncated}}<p class="error-message">响应体超过 10 MB，只显示前 10 MB</p>{{end}}
+        <details>
+            <summary>响应头 ({{len .Headers}})</summary>
+            <ul>
+                {{range .Headers}}<li>{{.Name}}: {{.Value}}</li>{{end}}
+            </ul>
+        </details>
+        {{template "response-body" .Body}}
+        {{end}}
+        {{end}}
+    </div>
+    {{end}}
+</div>`
+
+// 将URL的协议和主机替换为目标地址，目标地址带路径时作为前缀
+func rewriteTarget(rawURL, target string) (string, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return rawURL, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("解析URL失败: %v", err)
+	}
+	t, err := url.Parse(target)
+	if err != nil || t.Scheme == "" || t.Host == "" {
+		return "", fmt.Errorf("目标地址格式应为 http://host:port")
+	}
+	u.Scheme, u.Host = t.Scheme, t.Host
+	if prefix := strings.TrimSuffix(t.Path, "/"); prefix != "" {
+		u.Path = prefix + u.Path
+		u.RawPath = ""
+	}
+	return u.String(), nil
+}
+
+// 发送请求并生成响应信息，请求失败时只填写 Error
+func sendReplay(req *snippetRequest) replayResponse {
+	result := replayResponse{URL: req.URL}
+
+	var body io.Reader
+	if req.Body != "" {
+		body = strings.NewReader(req.Body)
+	}
+	httpReq, err := http.NewRequest(req.Method, req.URL, body)
+	if err != nil {
+		result.Error = fmt.Sprintf("创建请求失败: %v", err)
+		return result
+	}
+	for _, header := range req.Headers {
+		// 由 net/http 协商压缩并自动解压
+		if strings.EqualFold(header.Name, "Accept-Encoding") {
+			continue
+		}
+		httpReq.Header.Set(header.Name, header.Value)
+	}
+
+	start := time.Now()
+	resp, err := replayClient.Do(httpReq)
+	if err != nil {
+		result.Error = fmt.Sprintf("发送请求失败: %v", err)
+		return result
+	}
+	defer resp.Body.Close()
+	// 多读一个字节判断是否超过上限
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxReplayBodySize+1))
+	result.Time = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		result.Error = fmt.Sprintf("读取响应失败: %v", err)
+		return result
+	}
+	if len(data) > maxReplayBodySize {
+		data, result.Truncated = data[:maxReplayBodySize], true
+	}
+
+	result.Status = resp.StatusCode
+	result.StatusText = strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode)+" ")
+	for name, values := range resp.Header {
+		for _, value := range values {
+			result.Headers = append(result.Headers, Header{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(result.Headers, func(i, j int) bool { return result.Headers[i].Name < result.Headers[j].Name })
+
+	content := Content{Size: int64(len(data)), MimeType: resp.Header.Get("Content-Type"), Text: string(data)}
+	if !isPrintableText(data) {
+		content.Text = base64.StdEncoding.EncodeToString(data)
+		content.Encoding = "base64"
+	}
+	result.Body = buildBodyView(content, result.Headers)
+	return result
+}
+
+// 判断请求是否来自本服务的页面，防止其他网站通过浏览器让本服务向任意地址发送请求
+func sameOrigin(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	u, err := url.Parse(source)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+// 重放请求处理函数，target 参数可将请求发送到其他主机
+func replayHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "请使用POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "只能从请求详情页面重放", http.StatusForbidden)
+		return
+	}
+	entry, ok := entryFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	req := newSnippetRequest(&entry.Request)
+	replayed := replayResponse{URL: req.URL}
+	if target, err := rewriteTarget(req.URL, r.FormValue("target")); err != nil {
+		replayed.Error = err.Error()
+	} else {
+		req.URL = target
+		replayed = sendReplay(req)
+	}
+
+	recorded := replayResponse{
+		URL:        entry.Request.URL,
+		Status:     entry.Response.Status,
+		StatusText: entry.Response.StatusText,
+		Time:       entry.Time,
+		Headers:    entry.Response.Headers,
+		Body:       buildBodyView(entry.Response.Content, entry.Response.Headers),
+	}
+
+	tmpl, err := template.New("replay").Parse(replayTemplate)
+	if err == nil {
+		_, err = tmpl.New("response-body").Parse(responseBodyTemplate)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("解析模板失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	tmpl.Execute(w, []struct {
+		Title    string
+		Response replayResponse
+	}{
+		{"录制的响应", recorded},
+		{"重放的响应", replayed},
+	})
+}
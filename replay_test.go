@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// 响应体超过上限时截断并标记
+func TestSendReplayTruncated(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size := maxReplayBodySize
+		if r.URL.Path == "/large" {
+			size++
+		}
+		w.Write([]byte(strings.Repeat("x", size)))
+	}))
+	defer upstream.Close()
+
+	for _, test := range []struct {
+		path      string
+		truncated bool
+	}{
+		{"/exact", false},
+		{"/large", true},
+	} {
+		result := sendReplay(&snippetRequest{Method: "GET", URL: upstream.URL + test.path})
+		if result.Error != "" || result.Truncated != test.truncated || len(result.Body.Raw) != maxReplayBodySize {
+			t.Errorf("%s: 截断 %v，大小 %d，错误 %q", test.path, result.Truncated, len(result.Body.Raw), result.Error)
+		}
+	}
+}
+
+// 只接受来自本服务页面的重放请求
+func TestReplaySameOrigin(t *testing.T) {
+	server, client := newTestServer(t)
+	sid := store.newSession()
+	defer store.closeAll(sid)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "replayed")
+	}))
+	defer upstream.Close()
+
+	fid, err := uploadTestHAR(client, server.URL, sid, testHAR(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		status int
+	}{
+		{"无来源", "", "", http.StatusForbidden},
+		{"其他网站", "Origin", "https://evil.example", http.StatusForbidden},
+		{"同源", "Origin", server.URL, http.StatusOK},
+		{"同源页面", "Referer", server.URL + "/?sid=" + sid, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			form := url.Values{"target": {upstream.URL}}
+			req, _ := http.NewRequest("POST", server.URL+"/replay?sid="+sid+"&fid="+fid+"&index=0", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if test.header != "" {
+				req.Header.Set(test.header, test.value)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != test.status {
+				t.Errorf("状态码为 %d，应为 %d", resp.StatusCode, test.status)
+			}
+			if replayed := strings.Contains(string(body), "replayed"); replayed != (test.status == http.StatusOK) {
+				t.Errorf("重放结果为 %s", body)
+			}
+		})
+	}
+}
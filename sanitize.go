@@ -0,0 +1,528 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// 替换敏感内容使用的文本，不含需要URL编码的字符
+const redactedValue = "REDACTED"
+
+// 预览中最多列出的替换项数量
+const maxRedactionPreview = 500
+
+// 按名称整体替换值的请求头和响应头
+var sensitiveHeaders = map[string]bool{
+	"authorization":        true,
+	"proxy-authorization":  true,
+	"cookie":               true,
+	"set-cookie":           true,
+	"x-api-key":            true,
+	"x-auth-token":         true,
+	"x-access-token":       true,
+	"x-csrf-token":         true,
+	"x-xsrf-token":         true,
+	"x-amz-security-token": true,
+}
+
+// 值为URL的请求头和响应头，按URL处理其中的查询参数
+var urlHeaders = map[string]bool{
+	":path":            true,
+	"referer":          true,
+	"location":         true,
+	"origin":           true,
+	"content-location": true,
+}
+
+// 按名称替换值的查询参数、表单字段和JSON字段（不区分大小写）
+var sensitiveParams = []string{
+	"password", "passwd", "pwd", "secret", "client_secret",
+	"token", "access_token", "refresh_token", "id_token", "auth_token",
+	"api_key", "apikey", "access_key", "session", "sessionid", "session_id",
+	"signature", "sig", "code", "otp", "csrf_token", "xsrf_token",
+}
+
+// 内置规则
+type redactRule struct {
+	Key     string
+	Name    string
+	pattern *regexp.Regexp // 为空时表示按名称匹配的规则
+	group   int            // 只替换指定分组，0 表示整个匹配
+}
+
+var builtinRedactRules = []redactRule{
+	{Key: "headers", Name: "认证请求头/Set-Cookie"},
+	{Key: "cookies", Name: "Cookie"},
+	{Key: "params", Name: "令牌/密码参数"},
+	{Key: "json", Name: "JSON中的令牌/密码字段",
+		pattern: regexp.MustCompile(`(?i)"(?:` + strings.Join(sensitiveParams, "|") + `)"\s*:\s*"((?:[^"\\]|\\.)*)"`), group: 1},
+	{Key: "jwt", Name: "JWT",
+		pattern: regexp.MustCompile(`eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*`)},
+	{Key: "bearer", Name: "Bearer令牌",
+		pattern: regexp.MustCompile(`(?i)bearer\s+([A-Za-z0-9._~+/-]+=*)`), group: 1},
+	{Key: "email", Name: "邮箱地址",
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+}
+
+// 一处替换
+type redaction struct {
+	Index    int
+	Location string
+	Rule     string
+	Original string
+}
+
+// 脱敏器，按规则替换条目中的敏感内容并记录替换位置
+type sanitizer struct {
+	enabled  map[string]bool
+	patterns []redactRule // 作用于所有文本的正则规则
+	params   map[string]bool
+	Found    []redaction
+	Counts   map[string]int
+	Total    int
+}
+
+// 创建脱敏器，rules 为启用的内置规则，custom 为每行一个的自定义正则表达式
+func newSanitizer(rules []string, custom string) (*sanitizer, error) {
+	s := &sanitizer{
+		enabled: make(map[string]bool),
+		params:  make(map[string]bool),
+		Counts:  make(map[string]int),
+	}
+	for _, key := range rules {
+		s.enabled[key] = true
+	}
+	for _, name := range sensitiveParams {
+		s.params[name] = true
+	}
+	for _, rule := range builtinRedactRules {
+		if rule.pattern != nil && s.enabled[rule.Key] {
+			s.patterns = append(s.patterns, rule)
+		}
+	}
+	for _, line := range strings.Split(custom, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		re, err := regexp.Compile(line)
+		if err != nil {
+			return nil, fmt.Errorf("自定义规则 %q 错误: %v", line, err)
+		}
+		rule := redactRule{Key: "custom", Name: "自定义: " + line, pattern: re}
+		// 包含分组时只替换第一个分组
+		if re.NumSubexp() > 0 {
+			rule.group = 1
+		}
+		s.patterns = append(s.patterns, rule)
+	}
+	return s, nil
+}
+
+// 记录一处替换
+func (s *sanitizer) record(index int, location, rule, original string) {
+	s.Total++
+	s.Counts[rule]++
+	if len(s.Found) < maxRedactionPreview {
+		if len([]rune(original)) > 80 {
+			original = string([]rune(original)[:80]) + "..."
+		}
+		s.Found = append(s.Found, redaction{Index: index, Location: location, Rule: rule, Original: original})
+	}
+}
+
+// 对文本应用正则规则
+func (s *sanitizer) text(index int, location, value string) string {
+	for _, rule := range s.patterns {
+		matches := rule.pattern.FindAllStringSubmatchIndex(value, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[2*rule.group], m[2*rule.group+1]
+			if start < 0 || start == end || value[start:end] == redactedValue {
+				continue
+			}
+			s.record(index, location, rule.Name, value[start:end])
+			b.WriteString(value[last:start])
+			b.WriteString(redactedValue)
+			last = end
+		}
+		b.WriteString(value[last:])
+		value = b.String()
+	}
+	return value
+}
+
+// 判断参数名是否敏感
+func (s *sanitizer) sensitiveParam(name string) bool {
+	return s.enabled["params"] && s.params[strings.ToLower(name)]
+}
+
+// 替换查询字符串或urlencoded表单中的敏感参数，其余参数解码后应用正则规则，保持参数顺序
+func (s *sanitizer) query(index int, location, raw string) string {
+	if raw == "" {
+		return raw
+	}
+	pairs := strings.Split(raw, "&")
+	for i, pair := range pairs {
+		key, value, found := strings.Cut(pair, "=")
+		if !found || value == "" {
+			continue
+		}
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		decoded, err := url.QueryUnescape(value)
+		if err != nil {
+			decoded = value
+		}
+		if s.sensitiveParam(name) {
+			if decoded != redactedValue {
+				s.record(index, location+" 参数 "+name, "令牌/密码参数", decoded)
+				pairs[i] = key + "=" + redactedValue
+			}
+			continue
+		}
+		// 编码后的值（如 %40）不能直接匹配正则规则
+		if redacted := s.text(index, location+" 参数 "+name, decoded); redacted != decoded {
+			pairs[i] = key + "=" + url.QueryEscape(redacted)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// 替换URL中的敏感查询参数，并应用正则规则
+func (s *sanitizer) url(index int, location, rawURL string) string {
+	if base, query, found := strings.Cut(rawURL, "?"); found {
+		fragment := ""
+		if i := strings.Index(query, "#"); i >= 0 {
+			query, fragment = query[:i], query[i:]
+		}
+		rawURL = base + "?" + s.query(index, location, query) + fragment
+	}
+	return s.text(index, location, rawURL)
+}
+
+// 处理请求头或响应头
+func (s *sanitizer) headers(index int, location string, headers []Header) {
+	for i := range headers {
+		header := &headers[i]
+		s.extra(index, location+" "+header.Name, &header.Comment, &header.Custom)
+		name := strings.ToLower(header.Name)
+		if s.enabled["headers"] && sensitiveHeaders[name] {
+			if header.Value != "" && header.Value != redactedValue {
+				s.record(index, location+" "+header.Name, "认证请求头/Set-Cookie", header.Value)
+				header.Value = redactedValue
+			}
+			continue
+		}
+		if urlHeaders[name] {
+			header.Value = s.url(index, location+" "+header.Name, header.Value)
+			continue
+		}
+		header.Value = s.text(index, location+" "+header.Name, header.Value)
+	}
+}
+
+// 处理 cookies 字段
+func (s *sanitizer) cookies(index int, location string, cookies []Cookie) {
+	for i := range cookies {
+		cookie := &cookies[i]
+		s.extra(index, location+" "+cookie.Name, &cookie.Comment, &cookie.Custom)
+		if s.enabled["cookies"] && cookie.Value != "" {
+			s.record(index, location+" "+cookie.Name, "Cookie", cookie.Value)
+			cookie.Value = redactedValue
+		}
+	}
+}
+
+// 处理注释和自定义字段，自定义字段替换为新的map，不修改内存中的索引
+func (s *sanitizer) extra(index int, location string, comment *string, custom *CustomFields) {
+	if *comment != "" {
+		*comment = s.url(index, location+" 注释", *comment)
+	}
+	if len(*custom) == 0 {
+		return
+	}
+	fields := make(CustomFields, len(*custom))
+	for key, raw := range *custom {
+		fields[key] = raw
+		var value any
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if decoder.Decode(&value) != nil {
+			continue
+		}
+		changed := false
+		value = s.jsonValue(index, location+" "+key, value, &changed)
+		if !changed {
+			continue
+		}
+		if data, err := json.Marshal(value); err == nil {
+			fields[key] = data
+		}
+	}
+	*custom = fields
+}
+
+// 处理自定义字段中的JSON值，字符串按URL处理，敏感字段名的值整体替换
+func (s *sanitizer) jsonValue(index int, location string, value any, changed *bool) any {
+	switch v := value.(type) {
+	case string:
+		result := s.url(index, location, v)
+		if result != v {
+			*changed = true
+		}
+		return result
+	case map[string]any:
+		for key, item := range v {
+			if text, ok := item.(string); ok && s.enabled["json"] && s.params[strings.ToLower(key)] {
+				if text != "" && text != redactedValue {
+					s.record(index, location+" 字段 "+key, "JSON中的令牌/密码字段", text)
+					v[key] = redactedValue
+					*changed = true
+				}
+				continue
+			}
+			v[key] = s.jsonValue(index, location, item, changed)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = s.jsonValue(index, location, item, changed)
+		}
+	}
+	return value
+}
+
+// 处理base64编码的内容，解码后为文本时应用正则规则并重新编码，二进制内容不处理
+func (s *sanitizer) base64Text(index int, location, text string) string {
+	data, err := decodeBase64(text)
+	if err != nil || !isPrintableText(data) {
+		return text
+	}
+	decoded := string(data)
+	if redacted := s.text(index, location, decoded); redacted != decoded {
+		return base64.StdEncoding.EncodeToString([]byte(redacted))
+	}
+	return text
+}
+
+// 处理缓存信息
+func (s *sanitizer) cacheEntry(index int, location string, entry *CacheEntry) *CacheEntry {
+	if entry == nil {
+		return nil
+	}
+	result := *entry
+	s.extra(index, location, &result.Comment, &result.Custom)
+	return &result
+}
+
+// 脱敏日志中条目以外的部分：注释、自定义字段、creator、browser 和页面
+func (s *sanitizer) log(harLog Log) Log {
+	s.extra(-1, "日志", &harLog.Comment, &harLog.Custom)
+	s.extra(-1, "creator", &harLog.Creator.Comment, &harLog.Creator.Custom)
+	if harLog.Browser != nil {
+		browser := *harLog.Browser
+		s.extra(-1, "browser", &browser.Comment, &browser.Custom)
+		harLog.Browser = &browser
+	}
+	harLog.Pages = slices.Clone(harLog.Pages)
+	for i := range harLog.Pages {
+		page := &harLog.Pages[i]
+		location := "页面 " + page.ID
+		page.Title = s.url(-1, location+" 标题", page.Title)
+		s.extra(-1, location, &page.Comment, &page.Custom)
+		s.extra(-1, location+" pageTimings", &page.PageTimings.Comment, &page.PageTimings.Custom)
+	}
+	return harLog
+}
+
+// 脱敏单个完整条目，直接修改传入的条目
+func (s *sanitizer) entry(index int, e *Entry) {
+	s.extra(index, "条目", &e.Comment, &e.Custom)
+
+	req := &e.Request
+	s.extra(index, "请求", &req.Comment, &req.Custom)
+	req.URL = s.url(index, "URL", req.URL)
+	for i := range req.QueryString {
+		param := &req.QueryString[i]
+		s.extra(index, "查询参数 "+param.Name, &param.Comment, &param.Custom)
+		if s.sensitiveParam(param.Name) {
+			// URL 中已记录，这里只替换
+			param.Value = redactedValue
+			continue
+		}
+		param.Value = s.text(index, "查询参数 "+param.Name, param.Value)
+	}
+	s.headers(index, "请求头", req.Headers)
+	s.cookies(index, "请求Cookie", req.Cookies)
+
+	if req.PostData != nil {
+		postData := *req.PostData
+		postData.Params = slices.Clone(postData.Params)
+		req.PostData = &postData
+		s.extra(index, "请求体", &postData.Comment, &postData.Custom)
+		for i := range postData.Params {
+			param := &postData.Params[i]
+			s.extra(index, "表单字段 "+param.Name, &param.Comment, &param.Custom)
+			if param.Value != "" && s.sensitiveParam(param.Name) {
+				s.record(index, "表单字段 "+param.Name, "令牌/密码参数", param.Value)
+				param.Value = redactedValue
+				continue
+			}
+			param.Value = s.text(index, "表单字段 "+param.Name, param.Value)
+		}
+		if baseMimeType(postData.MimeType) == "application/x-www-form-urlencoded" {
+			postData.Text = s.query(index, "请求体", postData.Text)
+		}
+		postData.Text = s.text(index, "请求体", postData.Text)
+	}
+
+	resp := &e.Response
+	s.extra(index, "响应", &resp.Comment, &resp.Custom)
+	s.headers(index, "响应头", resp.Headers)
+	s.cookies(index, "响应Cookie", resp.Cookies)
+	resp.RedirectURL = s.url(index, "重定向地址", resp.RedirectURL)
+	s.extra(index, "响应体", &resp.Content.Comment, &resp.Content.Custom)
+	if strings.EqualFold(resp.Content.Encoding, "base64") {
+		resp.Content.Text = s.base64Text(index, "响应体", resp.Content.Text)
+	} else {
+		resp.Content.Text = s.text(index, "响应体", resp.Content.Text)
+	}
+
+	s.extra(index, "缓存", &e.Cache.Comment, &e.Cache.Custom)
+	e.Cache.BeforeRequest = s.cacheEntry(index, "缓存 beforeRequest", e.Cache.BeforeRequest)
+	e.Cache.AfterRequest = s.cacheEntry(index, "缓存 afterRequest", e.Cache.AfterRequest)
+	s.extra(index, "耗时", &e.Timings.Comment, &e.Timings.Custom)
+}
+
+// 返回读取并脱敏文件条目的函数
+func (s *sanitizer) fileEntry(file *harFile) func(i int) (*Entry, error) {
+	return func(i int) (*Entry, error) {
+		e, err := file.Entry(i)
+		if err != nil {
+			return nil, err
+		}
+		// 复制切片，避免修改内存中的索引
+		e.Request.Headers = slices.Clone(e.Request.Headers)
+		e.Request.Cookies = slices.Clone(e.Request.Cookies)
+		e.Request.QueryString = slices.Clone(e.Request.QueryString)
+		e.Response.Headers = slices.Clone(e.Response.Headers)
+		e.Response.Cookies = slices.Clone(e.Response.Cookies)
+		s.entry(i, &e)
+		return &e, nil
+	}
+}
+
+// 替换统计
+type redactionCount struct {
+	Rule  string
+	Count int
+}
+
+var sanitizePreviewTemplate = `<div class="sanitize-preview">
+    {{if .Error}}
+    <p class="error-message">{{.Error}}</p>
+    {{else if not .Total}}
+    <p>没有需要替换的内容</p>
+    {{else}}
+    <p>共 {{.Total}} 处将被替换为 ` + redactedValue + `{{if gt .Total (len .Found)}}，下面列出前 {{len .Found}} 处{{end}}</p>
+    <ul>
+        {{range .Counts}}<li>{{.Rule}}: {{.Count}} 处</li>{{end}}
+    </ul>
+    <table class="param-table">
+        <tr><th>序号</th><th>位置</th><th>规则</th><th>原内容</th></tr>
+        {{range .Found}}<tr><td>{{.Index}}</td><td>{{.Location}}</td><td>{{.Rule}}</td><td>{{.Original}}</td></tr>{{end}}
+    </table>
+    {{end}}
+</div>`
+
+// 根据请求参数创建脱敏器
+func sanitizerFromRequest(r *http.Request) (*sanitizer, error) {
+	r.ParseForm()
+	return newSanitizer(r.Form["rule"], r.FormValue("custom"))
+}
+
+// 脱敏预览处理函数，返回将被替换的内容
+func sanitizePreviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "请使用POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+	_, file, ok := fileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tmpl, err := template.New("sanitize-preview").Parse(sanitizePreviewTemplate)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("解析模板失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{}
+	s, err := sanitizerFromRequest(r)
+	if err != nil {
+		data["Error"] = err.Error()
+		tmpl.Execute(w, data)
+		return
+	}
+	s.log(file.HAR.Log)
+	entry := s.fileEntry(file)
+	for i := range file.HAR.Log.Entries {
+		if _, err := entry(i); err != nil {
+			data["Error"] = err.Error()
+			tmpl.Execute(w, data)
+			return
+		}
+	}
+
+	counts := make([]redactionCount, 0, len(s.Counts))
+	for rule, count := range s.Counts {
+		counts = append(counts, redactionCount{rule, count})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Count > counts[j].Count })
+	data["Total"] = s.Total
+	data["Found"] = s.Found
+	data["Counts"] = counts
+	tmpl.Execute(w, data)
+}
+
+// 下载脱敏后的HAR文件
+func sanitizeDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "请使用POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+	_, file, ok := fileFromRequest(w, r)
+	if !ok {
+		return
+	}
+	s, err := sanitizerFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSuffix(file.Name, ".har") + "-sanitized.har"
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(name)))
+
+	// 中途出错时中断连接，不能让用户拿到不完整的脱敏文件
+	if err := writeHAR(w, s.log(file.HAR.Log), len(file.HAR.Log.Entries), s.fileEntry(file)); err != nil {
+		abortExport(name, err)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// 创建用于脱敏测试的条目
+func sanitizeTestEntry() Entry {
+	return Entry{
+		Request: Request{
+			Method: "POST",
+			URL:    "https://example.com/login?token=abc123&page=2",
+			Headers: []Header{
+				{Name: "Authorization", Value: "Basic dXNlcjpwYXNz"},
+				{Name: "Referer", Value: "https://example.com/home?session=s1&tab=a"},
+				{Name: "Accept", Value: "application/json"},
+			},
+			Cookies:     []Cookie{{Name: "sid", Value: "cookie-value"}},
+			QueryString: []QueryString{{Name: "token", Value: "abc123"}, {Name: "page", Value: "2"}},
+			PostData: &PostData{
+				MimeType: "application/x-www-form-urlencoded",
+				Text:     "user=bob&password=hunter2&mail=bob%40example.com",
+				Params:   []Param{{Name: "user", Value: "bob"}, {Name: "password", Value: "hunter2"}},
+			},
+		},
+		Response: Response{
+			Status: 200,
+			Headers: []Header{
+				{Name: "Set-Cookie", Value: "sid=new-value; Path=/"},
+				{Name: "Location", Value: "/next?code=xyz"},
+			},
+			Cookies: []Cookie{{Name: "sid", Value: "new-value"}},
+			Content: Content{
+				MimeType: "application/json",
+				Text:     `{"access_token":"tok-1","user":{"email":"bob@example.com"}}`,
+			},
+		},
+	}
+}
+
+func TestSanitizeRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []string
+		check func(e *Entry) string // 返回被检查的内容
+		want  string
+	}{
+		{"认证请求头", []string{"headers"}, func(e *Entry) string { return e.Request.Headers[0].Value }, redactedValue},
+		{"Set-Cookie", []string{"headers"}, func(e *Entry) string { return e.Response.Headers[0].Value }, redactedValue},
+		{"普通请求头不变", []string{"headers"}, func(e *Entry) string { return e.Request.Headers[2].Value }, "application/json"},
+		{"请求头规则未启用", nil, func(e *Entry) string { return e.Request.Headers[0].Value }, "Basic dXNlcjpwYXNz"},
+		{"请求Cookie", []string{"cookies"}, func(e *Entry) string { return e.Request.Cookies[0].Value }, redactedValue},
+		{"响应Cookie", []string{"cookies"}, func(e *Entry) string { return e.Response.Cookies[0].Value }, redactedValue},
+		{"URL查询参数", []string{"params"}, func(e *Entry) string { return e.Request.URL }, "https://example.com/login?token=" + redactedValue + "&page=2"},
+		{"queryString", []string{"params"}, func(e *Entry) string { return e.Request.QueryString[0].Value }, redactedValue},
+		{"普通查询参数不变", []string{"params"}, func(e *Entry) string { return e.Request.QueryString[1].Value }, "2"},
+		{"Referer中的参数", []string{"params"}, func(e *Entry) string { return e.Request.Headers[1].Value }, "https://example.com/home?session=" + redactedValue + "&tab=a"},
+		{"Location中的参数", []string{"params"}, func(e *Entry) string { return e.Response.Headers[1].Value }, "/next?code=" + redactedValue},
+		{"表单请求体", []string{"params"}, func(e *Entry) string { return e.Request.PostData.Text }, "user=bob&password=" + redactedValue + "&mail=bob%40example.com"},
+		{"表单字段", []string{"params"}, func(e *Entry) string { return e.Request.PostData.Params[1].Value }, redactedValue},
+		{"编码后的表单值", []string{"email"}, func(e *Entry) string { return e.Request.PostData.Text }, "user=bob&password=hunter2&mail=" + redactedValue},
+		{"JSON字段", []string{"json"}, func(e *Entry) string { return e.Response.Content.Text }, `{"access_token":"` + redactedValue + `","user":{"email":"bob@example.com"}}`},
+		{"JSON中的邮箱", []string{"email"}, func(e *Entry) string { return e.Response.Content.Text }, `{"access_token":"tok-1","user":{"email":"` + redactedValue + `"}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := newSanitizer(test.rules, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry := sanitizeTestEntry()
+			s.entry(0, &entry)
+			if got := test.check(&entry); got != test.want {
+				t.Errorf("结果为 %q，应为 %q", got, test.want)
+			}
+		})
+	}
+}
+
+// base64编码的文本响应体解码后处理并重新编码，二进制内容不变
+func TestSanitizeBase64Content(t *testing.T) {
+	s, err := newSanitizer([]string{"email", "jwt"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := sanitizeTestEntry()
+	entry.Response.Content = Content{
+		MimeType: "text/plain",
+		Encoding: "base64",
+		Text:     base64.StdEncoding.EncodeToString([]byte("contact bob@example.com")),
+	}
+	s.entry(0, &entry)
+	data, err := base64.StdEncoding.DecodeString(entry.Response.Content.Text)
+	if err != nil || string(data) != "contact "+redactedValue {
+		t.Errorf("base64 响应体脱敏结果为 %q %v", data, err)
+	}
+
+	binary := base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G', 0x00, 0x01})
+	entry.Response.Content.Text = binary
+	s.entry(0, &entry)
+	if entry.Response.Content.Text != binary {
+		t.Error("二进制响应体被修改")
+	}
+}
+
+// 自定义规则包含分组时只替换分组
+func TestSanitizeCustomRule(t *testing.T) {
+	s, err := newSanitizer(nil, `orderId=(\d+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := sanitizeTestEntry()
+	entry.Response.Content.Text = "orderId=12345&x=1"
+	s.entry(0, &entry)
+	if want := "orderId=" + redactedValue + "&x=1"; entry.Response.Content.Text != want {
+		t.Errorf("结果为 %q，应为 %q", entry.Response.Content.Text, want)
+	}
+	if _, err := newSanitizer(nil, "("); err == nil {
+		t.Error("错误的自定义规则未报错")
+	}
+}
+
+// 下载的脱敏文件中不含敏感内容，内存中的条目不被修改
+func TestSanitizeDownload(t *testing.T) {
+	server, client := newTestServer(t)
+	sid := store.newSession()
+	defer store.closeAll(sid)
+
+	entry := sanitizeTestEntry()
+	entry.StartedDateTime = "2024-01-01T00:00:00Z"
+	data, _ := json.Marshal(HAR{Log: Log{Version: "1.2", Comment: "see https://example.com/?token=abc123", Entries: []Entry{entry}}})
+	fid, err := uploadTestHAR(client, server.URL, sid, string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{"rule": {"headers", "cookies", "params", "json"}}
+	resp, err := client.PostForm(server.URL+"/sanitize/download?sid="+sid+"&fid="+fid, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sanitized HAR
+	if err := json.Unmarshal(body, &sanitized); err != nil {
+		t.Fatalf("下载的文件不是有效的HAR: %v", err)
+	}
+	for _, secret := range []string{"abc123", "dXNlcjpwYXNz", "cookie-value", "hunter2", "tok-1", "xyz"} {
+		if strings.Contains(string(body), secret) {
+			t.Errorf("下载的文件中包含 %q", secret)
+		}
+	}
+
+	file := store.file(sid, fid)
+	original, err := file.Entry(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original.Request.Headers[0].Value != "Basic dXNlcjpwYXNz" || original.Response.Content.Text != entry.Response.Content.Text {
+		t.Error("脱敏修改了内存中的条目")
+	}
+}
+
+// 读取临时文件失败时中断下载，不返回不完整的文件
+func TestSanitizeDownloadReadError(t *testing.T) {
+	server, client := newTestServer(t)
+	sid := store.newSession()
+	defer store.closeAll(sid)
+
+	fid, err := uploadTestHAR(client, server.URL, sid, testHAR(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.file(sid, fid).spill.Close()
+
+	resp, err := client.PostForm(server.URL+"/sanitize/download?sid="+sid+"&fid="+fid, url.Values{"rule": {"headers"}})
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); err == nil {
+		t.Errorf("读取失败时仍返回了完整的响应: %s", resp.Status)
+	}
+}
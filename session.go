@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 会话空闲超过该时间后在创建新会话时清理
+const sessionIdleTimeout = 2 * time.Hour
+
+// 最多保留的会话数量，超出时清理最久未访问的会话
+const maxSessions = 64
+
+// 工作区会话，每个浏览器标签页通过URL中的 sid 使用独立的会话
+// 一个会话中可以同时加载多个HAR文件
+type session struct {
+	ID string
+	// 文件列表采用写时复制，修改时整体替换，读取方拿到的切片不会再被修改
+	files []*harFile
+	// 最近访问时间（UnixNano），持有读锁时也会更新
+	lastUsed atomic.Int64
+}
+
+// 会话存储，所有会话和文件列表的访问都需要经过它
+// 已加载的 harFile 在加入存储后不再修改，可以被多个请求同时读取
+type harStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*session
+}
+
+func newHARStore() *harStore {
+	return &harStore{sessions: make(map[string]*session)}
+}
+
+// 全局变量，存储所有会话
+var store = newHARStore()
+
+// 生成随机ID
+func newID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// 创建新会话，同时清理空闲超时的会话，会话数量超出上限时清理最久未访问的会话
+func (st *harStore) newSession() string {
+	s := &session{ID: newID()}
+	now := time.Now()
+	s.lastUsed.Store(now.UnixNano())
+
+	st.mu.Lock()
+	var evicted []*session
+	for id, old := range st.sessions {
+		if now.Sub(time.Unix(0, old.lastUsed.Load())) > sessionIdleTimeout {
+			evicted = append(evicted, old)
+			delete(st.sessions, id)
+		}
+	}
+	for len(st.sessions) >= maxSessions {
+		var oldest *session
+		for _, old := range st.sessions {
+			if oldest == nil || old.lastUsed.Load() < oldest.lastUsed.Load() {
+				oldest = old
+			}
+		}
+		evicted = append(evicted, oldest)
+		delete(st.sessions, oldest.ID)
+	}
+	st.sessions[s.ID] = s
+	st.mu.Unlock()
+
+	for _, old := range evicted {
+		stopMocks(old.ID, "", "")
+		for _, file := range old.files {
+			file.Close()
+		}
+	}
+	return s.ID
+}
+
+// 判断会话是否存在，并记录访问时间
+func (st *harStore) hasSession(sid string) bool {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	s, ok := st.sessions[sid]
+	if ok {
+		s.lastUsed.Store(time.Now().UnixNano())
+	}
+	return ok
+}
+
+// 获取会话中文件列表的快照
+func (st *harStore) files(sid string) []*harFile {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	if s, ok := st.sessions[sid]; ok {
+		return s.files
+	}
+	return nil
+}
+
+// 按ID查找文件
+func (st *harStore) file(sid, fid string) *harFile {
+	for _, file := range st.files(sid) {
+		if file.ID == fid {
+			return file
+		}
+	}
+	return nil
+}
+
+// 查找文件并登记为读取方，请求结束后自动释放，期间关闭文件不会影响临时文件的读取
+func (st *harStore) acquireFile(r *http.Request, sid, fid string) *harFile {
+	file := st.file(sid, fid)
+	if file == nil || !file.acquire() {
+		return nil
+	}
+	context.AfterFunc(r.Context(), file.release)
+	return file
+}
+
+// 添加文件，会话不存在时关闭文件并返回 false
+func (st *harStore) addFile(sid string, file *harFile) bool {
+	file.ID = newID()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s, ok := st.sessions[sid]
+	if !ok {
+		file.Close()
+		return false
+	}
+	files := make([]*harFile, 0, len(s.files)+1)
+	files = append(files, s.files...)
+	s.files = append(files, file)
+	return true
+}
+
+// 关闭并移除文件
+func (st *harStore) closeFile(sid, fid string) {
+	st.mu.Lock()
+	s, ok := st.sessions[sid]
+	if !ok {
+		st.mu.Unlock()
+		return
+	}
+	var closed *harFile
+	files := make([]*harFile, 0, len(s.files))
+	for _, file := range s.files {
+		if file.ID == fid {
+			closed = file
+			continue
+		}
+		files = append(files, file)
+	}
+	s.files = files
+	st.mu.Unlock()
+
+	closed.Close()
+}
+
+// 关闭会话中的所有文件
+func (st *harStore) closeAll(sid string) {
+	st.mu.Lock()
+	s, ok := st.sessions[sid]
+	if !ok {
+		st.mu.Unlock()
+		return
+	}
+	files := s.files
+	s.files = nil
+	st.mu.Unlock()
+
+	for _, file := range files {
+		file.Close()
+	}
+}
+
+// 生成会话页面地址
+func sessionURL(sid, fid string) string {
+	values := url.Values{}
+	values.Set("sid", sid)
+	if fid != "" {
+		values.Set("fid", fid)
+	}
+	return "/?" + values.Encode()
+}
+
+// 根据请求参数获取会话ID，会话不存在时返回空字符串
+func sessionFromRequest(r *http.Request) string {
+	sid := r.URL.Query().Get("sid")
+	if sid == "" || !store.hasSession(sid) {
+		return ""
+	}
+	return sid
+}
+
+// 根据请求参数获取会话中的文件
+func fileFromRequest(w http.ResponseWriter, r *http.Request) (string, *harFile, bool) {
+	sid := sessionFromRequest(r)
+	if sid == "" {
+		http.Error(w, "会话不存在", http.StatusNotFound)
+		return "", nil, false
+	}
+	file := store.acquireFile(r, sid, r.URL.Query().Get("fid"))
+	if file == nil {
+		http.Error(w, "未加载HAR文件", http.StatusNotFound)
+		return "", nil, false
+	}
+	return sid, file, true
+}
+
+// 关闭单个文件处理函数
+func closeFileHandler(w http.ResponseWriter, r *http.Request) {
+	sid := sessionFromRequest(r)
+	if sid == "" {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	stopMocks(sid, r.URL.Query().Get("fid"), "")
+	store.closeFile(sid, r.URL.Query().Get("fid"))
+	http.Redirect(w, r, sessionURL(sid, ""), http.StatusFound)
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var setupRoutesOnce sync.Once
+
+// 启动测试用的Web服务，不跟随重定向
+func newTestServer(t *testing.T) (*httptest.Server, *http.Client) {
+	setupRoutesOnce.Do(setupRoutes)
+	server := httptest.NewServer(http.DefaultServeMux)
+	t.Cleanup(server.Close)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	return server, client
+}
+
+// 生成包含 n 个条目的HAR文件，每个响应体都以 body-序号 开头，长度足以跨越多次读取
+func testHAR(n int) string {
+	var entries []string
+	for i := 0; i < n; i++ {
+		text := fmt.Sprintf("body-%d-%s", i, strings.Repeat("x", 4096))
+		entries = append(entries, fmt.Sprintf(`{"startedDateTime":"2024-01-01T00:00:00Z","time":%d,`+
+			`"request":{"method":"GET","url":"https://example.com/items/%d","httpVersion":"HTTP/1.1","headers":[],"queryString":[],"cookies":[],"headersSize":-1,"bodySize":0},`+
+			`"response":{"status":200,"statusText":"OK","httpVersion":"HTTP/1.1","headers":[],"cookies":[],"content":{"size":%d,"mimeType":"text/plain","text":%q},"redirectURL":"","headersSize":-1,"bodySize":-1},`+
+			`"cache":{},"timings":{"send":0,"wait":%d,"receive":0}}`, i, i, len(text), text, i))
+	}
+	return `{"log":{"version":"1.2","creator":{"name":"test","version":"1"},"entries":[` + strings.Join(entries, ",") + `]}}`
+}
+
+// 上传HAR文件，返回新文件的ID
+func uploadTestHAR(client *http.Client, serverURL, sid, har string) (string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, _ := mw.CreateFormFile("harfile", "test.har")
+	io.WriteString(part, har)
+	mw.Close()
+
+	resp, err := client.Post(serverURL+"/upload?sid="+sid, mw.FormDataContentType(), &buf)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", err
+	}
+	fid := location.Query().Get("fid")
+	if fid == "" {
+		return "", fmt.Errorf("上传失败: %s %s", resp.Status, location)
+	}
+	return fid, nil
+}
+
+// 并发上传、读取条目、导出和关闭文件，读取方要么得到完整结果，要么得到文件不存在
+func TestStoreConcurrentAccess(t *testing.T) {
+	server, client := newTestServer(t)
+	sid := store.newSession()
+	defer store.closeAll(sid)
+
+	const entries = 20
+	har := testHAR(entries)
+	var wg sync.WaitGroup
+	errs := make(chan error, 1000)
+	for worker := 0; worker < 6; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for round := 0; round < 3; round++ {
+				fid, err := uploadTestHAR(client, server.URL, sid, har)
+				if err != nil {
+					errs <- err
+					return
+				}
+				query := "?sid=" + sid + "&fid=" + fid
+
+				var readers sync.WaitGroup
+				for i := 0; i < entries; i += 4 {
+					readers.Add(3)
+					go func() {
+						defer readers.Done()
+						resp, err := client.Get(server.URL + "/api/entry" + query + fmt.Sprintf("&index=%d", i))
+						if err != nil {
+							errs <- err
+							return
+						}
+						defer resp.Body.Close()
+						if resp.StatusCode == http.StatusNotFound {
+							return
+						}
+						var entry Entry
+						if err := json.NewDecoder(resp.Body).Decode(&entry); err != nil {
+							errs <- fmt.Errorf("读取条目 %d 失败: %s %v", i, resp.Status, err)
+							return
+						}
+						if !strings.HasPrefix(entry.Response.Content.Text, fmt.Sprintf("body-%d-", i)) {
+							errs <- fmt.Errorf("条目 %d 的响应体不完整", i)
+						}
+					}()
+					go func() {
+						defer readers.Done()
+						resp, err := client.Get(server.URL + "/response-body" + query + fmt.Sprintf("&index=%d", i))
+						if err != nil {
+							errs <- err
+							return
+						}
+						defer resp.Body.Close()
+						body, _ := io.ReadAll(resp.Body)
+						if resp.StatusCode == http.StatusOK && !strings.Contains(string(body), fmt.Sprintf("body-%d-", i)) {
+							errs <- fmt.Errorf("响应体 %d 不完整", i)
+						}
+					}()
+					go func() {
+						defer readers.Done()
+						resp, err := client.Get(server.URL + "/export" + query + "&format=har")
+						if err != nil {
+							errs <- err
+							return
+						}
+						defer resp.Body.Close()
+						if resp.StatusCode == http.StatusNotFound {
+							return
+						}
+						var exported HAR
+						if err := json.NewDecoder(resp.Body).Decode(&exported); err != nil {
+							errs <- fmt.Errorf("导出失败: %s %v", resp.Status, err)
+							return
+						}
+						if len(exported.Log.Entries) != entries {
+							errs <- fmt.Errorf("导出了 %d 个条目", len(exported.Log.Entries))
+						}
+					}()
+				}
+
+				// 与读取同时关闭文件
+				resp, err := client.Get(server.URL + "/close" + query)
+				if err != nil {
+					errs <- err
+					return
+				}
+				resp.Body.Close()
+				readers.Wait()
+			}
+		}()
+	}
+
+	// 同时读取会话和文件列表
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			resp, err := client.Get(server.URL + "/api/files?sid=" + sid)
+			if err != nil {
+				errs <- err
+				return
+			}
+			resp.Body.Close()
+			store.hasSession(sid)
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if files := store.files(sid); len(files) != 0 {
+		t.Errorf("关闭后仍有 %d 个文件", len(files))
+	}
+}
+
+// 关闭文件时有读取方，临时文件在最后一个读取方释放后才删除
+func TestCloseWaitsForReaders(t *testing.T) {
+	file, err := loadHAR("test.har", strings.NewReader(testHAR(2)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sid := store.newSession()
+	defer store.closeAll(sid)
+	store.addFile(sid, file)
+	spillName := file.spill.Name()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	if store.acquireFile(r, sid, file.ID) == nil {
+		t.Fatal("未找到文件")
+	}
+	store.closeFile(sid, file.ID)
+
+	if store.acquireFile(r, sid, file.ID) != nil {
+		t.Error("关闭后仍能获取文件")
+	}
+	entry, err := file.Entry(1)
+	if err != nil || !strings.HasPrefix(entry.Response.Content.Text, "body-1-") {
+		t.Fatalf("关闭后读取条目失败: %v", err)
+	}
+	if _, err := os.Stat(spillName); err != nil {
+		t.Fatalf("读取方释放前临时文件已删除: %v", err)
+	}
+
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(spillName); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("读取方释放后临时文件未删除")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 空闲超时和超出数量上限的会话在创建新会话时被清理，其中的文件被关闭
+func TestSessionEviction(t *testing.T) {
+	file, err := loadHAR("test.har", strings.NewReader(testHAR(1)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idle := store.newSession()
+	store.addFile(idle, file)
+	store.mu.RLock()
+	store.sessions[idle].lastUsed.Store(time.Now().Add(-sessionIdleTimeout - time.Minute).UnixNano())
+	store.mu.RUnlock()
+
+	var created []string
+	for i := 0; i < maxSessions+5; i++ {
+		created = append(created, store.newSession())
+	}
+	defer func() {
+		for _, sid := range created {
+			store.closeAll(sid)
+		}
+	}()
+
+	if store.hasSession(idle) {
+		t.Error("空闲超时的会话未被清理")
+	}
+	if _, err := os.Stat(file.spill.Name()); !os.IsNotExist(err) {
+		t.Error("清理会话后文件未关闭")
+	}
+	store.mu.RLock()
+	n := len(store.sessions)
+	store.mu.RUnlock()
+	if n > maxSessions {
+		t.Errorf("会话数量 %d 超出上限", n)
+	}
+	if store.hasSession(created[0]) {
+		t.Error("最久未访问的会话未被清理")
+	}
+	if !store.hasSession(created[len(created)-1]) {
+		t.Error("最新的会话被清理")
+	}
+}
+
+// 按响应体过滤的同时关闭文件，页面要么显示完整的匹配结果，要么显示文件已关闭
+func TestIndexFilterWhileClosing(t *testing.T) {
+	server, client := newTestServer(t)
+	sid := store.newSession()
+	defer store.closeAll(sid)
+
+	const entries = 500
+	har := testHAR(entries)
+	for round := 0; round < 8; round++ {
+		fid, err := uploadTestHAR(client, server.URL, sid, har)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := "?sid=" + sid + "&fid=" + fid
+
+		done := make(chan string)
+		go func() {
+			resp, err := client.Get(server.URL + "/" + query + "&q=" + url.QueryEscape(`body:/^body-\d+-x+$/`))
+			if err != nil {
+				done <- err.Error()
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			done <- string(body)
+		}()
+		time.Sleep(time.Duration(round) * time.Millisecond)
+		resp, err := client.Get(server.URL + "/close" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		body := <-done
+		if strings.Contains(body, "匹配 ") && !strings.Contains(body, fmt.Sprintf("匹配 %d / %d 个请求", entries, entries)) {
+			t.Fatalf("第 %d 轮关闭文件后过滤结果不完整", round)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 生成代码片段和重放所需的请求信息
+type snippetRequest struct {
+	Method  string
+	URL     string
+	Headers []Header // 同名请求头已合并
+	Body    string
+}
+
+// 代码片段格式
+type snippetFormat struct {
+	Key      string
+	Name     string
+	generate func(req *snippetRequest) string
+}
+
+var snippetFormats = []snippetFormat{
+	{"curl", "cURL (bash)", curlBashSnippet},
+	{"curl-cmd", "cURL (cmd)", curlCmdSnippet},
+	{"wget", "wget", wgetSnippet},
+	{"powershell", "PowerShell", powerShellSnippet},
+	{"fetch", "fetch", fetchSnippet},
+	{"go", "Go net/http", goSnippet},
+	{"python", "Python requests", pythonSnippet},
+}
+
+// 由客户端自动生成的请求头，不写入代码片段
+var skippedSnippetHeaders = map[string]bool{
+	"content-length":    true,
+	"host":              true,
+	"connection":        true,
+	"transfer-encoding": true,
+}
+
+// 从HAR请求生成代码片段和重放所需的信息
+// 没有 Cookie 请求头时使用 cookies 字段，没有 postData.text 时使用 params 生成表单
+func newSnippetRequest(req *Request) *snippetRequest {
+	s := &snippetRequest{Method: req.Method, URL: req.URL}
+	if s.Method == "" {
+		s.Method = "GET"
+	}
+
+	hasCookie := false
+	for _, header := range req.Headers {
+		// HTTP/2 伪头部（如 :authority）不是真正的请求头
+		if strings.HasPrefix(header.Name, ":") || skippedSnippetHeaders[strings.ToLower(header.Name)] {
+			continue
+		}
+		if strings.EqualFold(header.Name, "Cookie") {
+			hasCookie = true
+		}
+		s.addHeader(header.Name, header.Value)
+	}
+	if !hasCookie && len(req.Cookies) > 0 {
+		cookies := make([]string, len(req.Cookies))
+		for i, cookie := range req.Cookies {
+			cookies[i] = cookie.Name + "=" + cookie.Value
+		}
+		s.addHeader("Cookie", strings.Join(cookies, "; "))
+	}
+
+	if req.PostData != nil {
+		s.Body = req.PostData.Text
+		if s.Body == "" && len(req.PostData.Params) > 0 {
+			values := url.Values{}
+			for _, param := range req.PostData.Params {
+				if param.FileName == "" {
+					values.Add(param.Name, param.Value)
+				}
+			}
+			s.Body = values.Encode()
+		}
+		if s.Body != "" && headerValue(s.Headers, "Content-Type") == "" && req.PostData.MimeType != "" {
+			s.addHeader("Content-Type", req.PostData.MimeType)
+		}
+	}
+	return s
+}
+
+// 添加请求头，同名请求头合并为一个（Cookie 用 "; " 连接，其余用 ", "）
+func (s *snippetRequest) addHeader(name, value string) {
+	for i, header := range s.Headers {
+		if strings.EqualFold(header.Name, name) {
+			separator := ", "
+			if strings.EqualFold(name, "Cookie") {
+				separator = "; "
+			}
+			s.Headers[i].Value += separator + value
+			return
+		}
+	}
+	s.Headers = append(s.Headers, Header{Name: name, Value: value})
+}
+
+// 是否需要显式指定请求方法
+func (s *snippetRequest) explicitMethod() bool {
+	return !(s.Method == "GET" && s.Body == "") && !(s.Method == "POST" && s.Body != "")
+}
+
+// bash 单引号字符串
+func bashQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// cmd 中需要转义的字符、环境变量引用和换行
+var (
+	cmdSpecialChars = regexp.MustCompile("[^a-zA-Z0-9\\s_\\-:=+~'/.,?;()*`\\x{80}-\\x{10FFFF}]")
+	cmdVariables    = regexp.MustCompile(`%([a-zA-Z0-9_])`)
+	cmdNewlines     = regexp.MustCompile(`\r?\n`)
+)
+
+// cmd 双引号字符串
+func cmdQuote(value string) string {
+	quote := `"`
+	if strings.ContainsAny(value, "\r\n") {
+		quote = `^"`
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = cmdSpecialChars.ReplaceAllString(value, "^$0")
+	value = cmdVariables.ReplaceAllString(value, "%^$1")
+	value = cmdNewlines.ReplaceAllString(value, "^\n\n")
+	return quote + value + quote
+}
+
+// PowerShell 单引号字符串
+func powerShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// JavaScript/Python 双引号字符串
+func jsQuote(value string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// 生成 cURL 命令，quote 为引号函数，continuation 为续行符
+func curlSnippet(req *snippetRequest, quote func(string) string, continuation string) string {
+	parts := []string{"curl " + quote(req.URL)}
+	if req.explicitMethod() {
+		parts = append(parts, "-X "+quote(req.Method))
+	}
+	for _, header := range req.Headers {
+		parts = append(parts, "-H "+quote(header.Name+": "+header.Value))
+	}
+	if req.Body != "" {
+		parts = append(parts, "--data-raw "+quote(req.Body))
+	}
+	if headerValue(req.Headers, "Accept-Encoding") != "" {
+		parts = append(parts, "--compressed")
+	}
+	return strings.Join(parts, " "+continuation+"\n  ")
+}
+
+func curlBashSnippet(req *snippetRequest) string {
+	return curlSnippet(req, bashQuote, `\`)
+}
+
+func curlCmdSnippet(req *snippetRequest) string {
+	return curlSnippet(req, cmdQuote, "^")
+}
+
+func wgetSnippet(req *snippetRequest) string {
+	parts := []string{"wget"}
+	if req.explicitMethod() {
+		parts = append(parts, "--method="+bashQuote(req.Method))
+	}
+	for _, header := range req.Headers {
+		parts = append(parts, "--header="+bashQuote(header.Name+": "+header.Value))
+	}
+	if req.Body != "" {
+		parts = append(parts, "--body-data="+bashQuote(req.Body))
+	}
+	parts = append(parts, "-O -", bashQuote(req.URL))
+	return strings.Join(parts, " \\\n  ")
+}
+
+// PowerShell 中 User-Agent 和 Content-Type 需要通过专门的参数设置
+func powerShellSnippet(req *snippetRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Invoke-WebRequest -UseBasicParsing -Uri %s `\n  -Method %s", powerShellQuote(req.URL), powerShellQuote(req.Method))
+
+	var headers []string
+	for _, header := range req.Headers {
+		switch strings.ToLower(header.Name) {
+		case "user-agent":
+			fmt.Fprintf(&b, " `\n  -UserAgent %s", powerShellQuote(header.Value))
+		case "content-type":
+			fmt.Fprintf(&b, " `\n  -ContentType %s", powerShellQuote(header.Value))
+		default:
+			headers = append(headers, "    "+powerShellQuote(header.Name)+" = "+powerShellQuote(header.Value))
+		}
+	}
+	if len(headers) > 0 {
+		fmt.Fprintf(&b, " `\n  -Headers @{\n%s\n  }", strings.Join(headers, "\n"))
+	}
+	if req.Body != "" {
+		fmt.Fprintf(&b, " `\n  -Body %s", powerShellQuote(req.Body))
+	}
+	return b.String()
+}
+
+func fetchSnippet(req *snippetRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "fetch(%s, {\n  method: %s,\n  headers: {\n", jsQuote(req.URL), jsQuote(req.Method))
+	for _, header := range req.Headers {
+		fmt.Fprintf(&b, "    %s: %s,\n", jsQuote(header.Name), jsQuote(header.Value))
+	}
+	b.WriteString("  },\n")
+	if req.Body != "" {
+		fmt.Fprintf(&b, "  body: %s,\n", jsQuote(req.Body))
+	}
+	b.WriteString("})\n  .then(response => response.text())\n  .then(text => console.log(text));")
+	return b.String()
+}
+
+func goSnippet(req *snippetRequest) string {
+	var b strings.Builder
+	b.WriteString("package main\n\nimport (\n\t\"fmt\"\n\t\"io\"\n\t\"net/http\"\n")
+	if req.Body != "" {
+		b.WriteString("\t\"strings\"\n")
+	}
+	b.WriteString(")\n\nfunc main() {\n")
+	if req.Body != "" {
+		fmt.Fprintf(&b, "\tbody := strings.NewReader(%s)\n", strconv.Quote(req.Body))
+		fmt.Fprintf(&b, "\treq, err := http.NewRequest(%s, %s, body)\n", strconv.Quote(req.Method), strconv.Quote(req.URL))
+	} else {
+		fmt.Fprintf(&b, "\treq, err := http.NewRequest(%s, %s, nil)\n", strconv.Quote(req.Method), strconv.Quote(req.URL))
+	}
+	b.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	for _, header := range req.Headers {
+		// 手动设置 Accept-Encoding 后 net/http 不再自动解压响应
+		if strings.EqualFold(header.Name, "Accept-Encoding") {
+			continue
+		}
+		fmt.Fprintf(&b, "\treq.Header.Set(%s, %s)\n", strconv.Quote(header.Name), strconv.Quote(header.Value))
+	}
+	b.WriteString("\n\tresp, err := http.DefaultClient.Do(req)\n\tif err != nil {\n\t\tpanic(err)\n\t}\n\tdefer resp.Body.Close()\n\n")
+	b.WriteString("\tdata, err := io.ReadAll(resp.Body)\n\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	b.WriteString("\tfmt.Println(resp.Status)\n\tfmt.Println(string(data))\n}\n")
+	return b.String()
+}
+
+func pythonSnippet(req *snippetRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "import requests\n\nurl = %s\nheaders = {\n", jsQuote(req.URL))
+	for _, header := range req.Headers {
+		fmt.Fprintf(&b, "    %s: %s,\n", jsQuote(header.Name), jsQuote(header.Value))
+	}
+	b.WriteString("}\n")
+	if req.Body != "" {
+		fmt.Fprintf(&b, "data = %s\n\n", jsQuote(req.Body))
+		fmt.Fprintf(&b, "response = requests.request(%s, url, headers=headers, data=data.encode(\"utf-8\"))\n", jsQuote(req.Method))
+	} else {
+		fmt.Fprintf(&b, "\nresponse = requests.request(%s, url, headers=headers)\n", jsQuote(req.Method))
+	}
+	b.WriteString("print(response.status_code)\nprint(response.text)\n")
+	return b.String()
+}
+
+// 代码片段处理函数，返回纯文本
+func snippetHandler(w http.ResponseWriter, r *http.Request) {
+	entry, ok := entryFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	for _, f := range snippetFormats {
+		if f.Key == format {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(w, f.generate(newSnippetRequest(&entry.Request)))
+			return
+		}
+	}
+	http.Error(w, "不支持的格式", http.StatusBadRequest)
+}
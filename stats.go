@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// 按域名分组时最多显示的数量，其余合并为一项
+const maxStatsDomains = 20
+
+// 分组统计中的一项，百分比用于绘制条形图
+type statsGroup struct {
+	Name         string
+	Count        int
+	Bytes        int64
+	Time         float64
+	CountPercent float64
+	BytesPercent float64
+}
+
+// 耗时百分位数
+type percentile struct {
+	Name    string
+	Value   float64
+	Percent float64 // 相对最大耗时的百分比
+}
+
+// 单个页面的统计
+type pageStats struct {
+	ID            string
+	Title         string
+	OnContentLoad float64
+	OnLoad        float64
+	Requests      int
+	Bytes         int64
+	Percent       float64 // onLoad 相对最慢页面的百分比
+}
+
+// 最慢的请求
+type slowEntry struct {
+	Index   int
+	Method  string
+	URL     string
+	Status  int
+	Time    float64
+	Percent float64
+}
+
+// 性能概览
+type perfStats struct {
+	Requests     int
+	Transferred  int64 // 传输大小（含头部，压缩后）
+	Uncompressed int64 // 解压后的响应体大小
+	TotalTime    float64
+	Percentiles  []percentile
+	Pages        []pageStats
+	Slowest      []slowEntry
+	MimeTypes    []statsGroup
+	Statuses     []statsGroup
+	Domains      []statsGroup
+}
+
+// 请求的传输大小，优先使用 Chrome 记录的 _transferSize
+func transferSize(entry *Entry) int64 {
+	if raw, ok := entry.Response.Custom["_transferSize"]; ok {
+		var size int64
+		if json.Unmarshal(raw, &size) == nil && size >= 0 {
+			return size
+		}
+	}
+	return max(entry.Response.HeadersSize, 0) + max(entry.Response.BodySize, 0)
+}
+
+// 按最近秩法计算百分位数，values 需已排序
+func percentileValue(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(values))))
+	return values[min(max(rank, 1), len(values))-1]
+}
+
+// 分组累计
+type groupCounter map[string]*statsGroup
+
+func (g groupCounter) add(name string, bytes int64, time float64) {
+	group, ok := g[name]
+	if !ok {
+		group = &statsGroup{Name: name}
+		g[name] = group
+	}
+	group.Count++
+	group.Bytes += bytes
+	group.Time += time
+}
+
+// 按数量从多到少排列并计算百分比，limit 大于 0 时将多余的项合并为"其他"
+func (g groupCounter) sorted(total int, totalBytes int64, limit int) []statsGroup {
+	groups := make([]statsGroup, 0, len(g))
+	for _, group := range g {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	if limit > 0 && len(groups) > limit {
+		other := statsGroup{Name: fmt.Sprintf("其他 %d 项", len(groups)-limit)}
+		for _, group := range groups[limit:] {
+			other.Count += group.Count
+			other.Bytes += group.Bytes
+			other.Time += group.Time
+		}
+		groups = append(groups[:limit], other)
+	}
+	for i := range groups {
+		if total > 0 {
+			groups[i].CountPercent = float64(groups[i].Count) / float64(total) * 100
+		}
+		if totalBytes > 0 {
+			groups[i].BytesPercent = float64(groups[i].Bytes) / float64(totalBytes) * 100
+		}
+	}
+	return groups
+}
+
+// 生成性能概览，top 为列出的最慢请求数量
+func buildPerfStats(harData *HAR, top int) *perfStats {
+	entries := harData.Log.Entries
+	stats := &perfStats{Requests: len(entries)}
+	mimeTypes, statuses, domains := groupCounter{}, groupCounter{}, groupCounter{}
+	pageIndex := make(map[string]int)
+	for i, page := range harData.Log.Pages {
+		pageIndex[page.ID] = i
+		stats.Pages = append(stats.Pages, pageStats{
+			ID:            page.ID,
+			Title:         page.Title,
+			OnContentLoad: page.PageTimings.OnContentLoad,
+			OnLoad:        page.PageTimings.OnLoad,
+		})
+	}
+
+	times := make([]float64, 0, len(entries))
+	slowest := make([]int, 0, len(entries))
+	for i := range entries {
+		entry := &entries[i]
+		transferred := transferSize(entry)
+		size := max(entry.Response.Content.Size, 0)
+		elapsed := max(entry.Time, 0)
+		stats.Transferred += transferred
+		stats.Uncompressed += size
+		stats.TotalTime += elapsed
+		times = append(times, elapsed)
+		slowest = append(slowest, i)
+
+		mimeType := baseMimeType(entry.Response.Content.MimeType)
+		if mimeType == "" {
+			mimeType = "(无)"
+		}
+		mimeTypes.add(mimeType, size, elapsed)
+		statuses.add(statusClass(entry.Response.Status), size, elapsed)
+		if domain := extractDomain(entry.Request.URL); domain != "" {
+			domains.add(domain, size, elapsed)
+		}
+		if p, ok := pageIndex[entry.Pageref]; ok {
+			stats.Pages[p].Requests++
+			stats.Pages[p].Bytes += transferred
+		}
+	}
+
+	// 百分位数
+	sort.Float64s(times)
+	var maxTime float64
+	if len(times) > 0 {
+		maxTime = times[len(times)-1]
+	}
+	for _, p := range []struct {
+		name  string
+		value float64
+	}{{"p50", 50}, {"p90", 90}, {"p99", 99}, {"最大", 100}} {
+		value := percentileValue(times, p.value)
+		item := percentile{Name: p.name, Value: value}
+		if maxTime > 0 {
+			item.Percent = value / maxTime * 100
+		}
+		stats.Percentiles = append(stats.Percentiles, item)
+	}
+
+	// 页面加载时间
+	var maxOnLoad float64
+	for _, page := range stats.Pages {
+		maxOnLoad = max(maxOnLoad, page.OnLoad)
+	}
+	for i := range stats.Pages {
+		if maxOnLoad > 0 && stats.Pages[i].OnLoad > 0 {
+			stats.Pages[i].Percent = stats.Pages[i].OnLoad / maxOnLoad * 100
+		}
+	}
+
+	// 最慢的请求
+	sort.SliceStable(slowest, func(a, b int) bool { return entries[slowest[a]].Time > entries[slowest[b]].Time })
+	for _, i := range slowest[:min(top, len(slowest))] {
+		entry := &entries[i]
+		slow := slowEntry{Index: i, Method: entry.Request.Method, URL: entry.Request.URL, Status: entry.Response.Status, Time: entry.Time}
+		if maxTime > 0 {
+			slow.Percent = max(entry.Time, 0) / maxTime * 100
+		}
+		stats.Slowest = append(stats.Slowest, slow)
+	}
+
+	stats.MimeTypes = mimeTypes.sorted(len(entries), stats.Uncompressed, 0)
+	stats.Statuses = statuses.sorted(len(entries), stats.Uncompressed, 0)
+	stats.Domains = domains.sorted(len(entries), stats.Uncompressed, maxStatsDomains)
+	return stats
+}
+
+var statsTemplate = `<div class="stats-dashboard">
+    <div class="stats-cards">
+        <div class="stats-card"><span>请求总数</span><strong>{{.Requests}}</strong></div>
+        <div class="stats-card"><span>传输大小</span><strong>{{formatSize .Transferred}}</strong></div>
+        <div class="stats-card"><span>解压后大小</span><strong>{{formatSize .Uncompressed}}</strong></div>
+        <div class="stats-card"><span>累计耗时</span><strong>{{printf "%.0f" .TotalTime}} ms</strong></div>
+    </div>
+
+    <h4>请求耗时分布</h4>
+    <table class="stats-chart">
+        {{range .Percentiles}}
+        <tr><th>{{.Name}}</th><td><div class="stats-bar"><span style="width: {{printf "%.2f" .Percent}}%;"></span></div></td><td>{{printf "%.2f" .Value}} ms</td></tr>
+        {{end}}
+    </table>
+
+    {{if .Pages}}
+    <h4>页面加载时间</h4>
+    <table class="stats-chart">
+        {{range .Pages}}
+        <tr>
+            <th title="{{.ID}}">{{if .Title}}{{.Title}}{{else}}{{.ID}}{{end}}</th>
+            <td><div class="stats-bar"><span style="width: {{printf "%.2f" .Percent}}%;"></span></div></td>
+            <td>onLoad {{if ge .OnLoad 0.0}}{{printf "%.0f" .OnLoad}} ms{{else}}-{{end}}，DOMContentLoaded {{if ge .OnContentLoad 0.0}}{{printf "%.0f" .OnContentLoad}} ms{{else}}-{{end}}，{{.Requests}} 个请求，{{formatSize .Bytes}}</td>
+        </tr>
+        {{end}}
+    </table>
+    {{end}}
+
+    <h4>最慢的 {{len .Slowest}} 个请求</h4>
+    <table class="stats-chart">
+        {{range .Slowest}}
+        <tr>
+            <th title="{{.URL}}">#{{.Index}} {{.Method}} {{.URL}}</th>
+            <td><div class="stats-bar stats-bar-slow"><span style="width: {{printf "%.2f" .Percent}}%;"></span></div></td>
+            <td>{{printf "%.2f" .Time}} ms ({{.Status}})</td>
+        </tr>
+        {{end}}
+    </table>
+
+    {{range .Groups}}
+    <h4>按{{.Title}}</h4>
+    <table class="stats-chart">
+        <tr><th></th><td>请求数 / 响应大小</td><td></td></tr>
+        {{range .Items}}
+        <tr>
+            <th title="{{.Name}}">{{.Name}}</th>
+            <td>
+                <div class="stats-bar"><span style="width: {{printf "%.2f" .CountPercent}}%;"></span></div>
+                <div class="stats-bar stats-bar-bytes"><span style="width: {{printf "%.2f" .BytesPercent}}%;"></span></div>
+            </td>
+            <td>{{.Count}} 个 ({{printf "%.1f" .CountPercent}}%)，{{formatSize .Bytes}}，平均 {{printf "%.2f" (average .Time .Count)}} ms</td>
+        </tr>
+        {{end}}
+    </table>
+    {{end}}
+</div>`
+
+// 性能概览处理函数，返回HTML片段
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	_, file, ok := fileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tmpl, err := template.New("stats").Funcs(template.FuncMap{
+		"formatSize": func(size int64) string { return formatFileSize(int(size)) },
+		"average": func(total float64, count int) float64 {
+			if count == 0 {
+				return 0
+			}
+			return total / float64(count)
+		},
+	}).Parse(statsTemplate)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("解析模板失败: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	stats := buildPerfStats(file.HAR, max(intParam(r, "top", 10), 0))
+	tmpl.Execute(w, struct {
+		*perfStats
+		Groups []struct {
+			Title string
+			Items []statsGroup
+		}
+	}{
+		perfStats: stats,
+		Groups: []struct {
+			Title string
+			Items []statsGroup
+		}{
+			{"MIME类型", stats.MimeTypes},
+			{"状态码", stats.Statuses},
+			{"域名", stats.Domains},
+		},
+	})
+}
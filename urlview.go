@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// URL 的各个组成部分
+type urlParts struct {
+	Scheme      string
+	Host        string
+	Port        string
+	DefaultPort bool // 端口为协议的默认端口，URL 中未写出
+	Path        string
+	Segments    []string // 解码后的路径段
+	Fragment    string
+	Params      []queryParam
+	Error       string
+}
+
+// 查询参数，FilterURL 为按该参数过滤请求列表的地址
+type queryParam struct {
+	Name      string
+	Value     string
+	FilterURL string
+}
+
+// 获取请求的查询参数（已解码），优先按 URL 中的顺序解析，URL 中没有时使用 HAR 的 queryString
+func requestQueryParams(req *Request) []formField {
+	if i := strings.Index(req.URL, "?"); i >= 0 {
+		query := req.URL[i+1:]
+		if j := strings.Index(query, "#"); j >= 0 {
+			query = query[:j]
+		}
+		if fields := parseFormFields(query); len(fields) > 0 {
+			return fields
+		}
+	}
+	fields := make([]formField, 0, len(req.QueryString))
+	for _, param := range req.QueryString {
+		field := formField{Name: param.Name, Value: param.Value}
+		if unescaped, err := url.QueryUnescape(field.Name); err == nil {
+			field.Name = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(field.Value); err == nil {
+			field.Value = unescaped
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// 生成按参数过滤的条件，参数值按字面匹配
+func paramFilter(name, value string) string {
+	return quoteFilterTerm("param:" + name + "=" + literalTextValue(value))
+}
+
+// 分解请求的URL
+func buildURLParts(sid, fid string, req *Request) urlParts {
+	parts := urlParts{}
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		parts.Error = err.Error()
+		return parts
+	}
+	parts.Scheme = u.Scheme
+	parts.Host = u.Hostname()
+	parts.Port = u.Port()
+	if parts.Port == "" {
+		switch strings.ToLower(u.Scheme) {
+		case "http", "ws":
+			parts.Port, parts.DefaultPort = "80", true
+		case "https", "wss":
+			parts.Port, parts.DefaultPort = "443", true
+		}
+	}
+	parts.Path = u.EscapedPath()
+	for _, segment := range strings.Split(strings.Trim(u.EscapedPath(), "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+		parts.Segments = append(parts.Segments, segment)
+	}
+	parts.Fragment = u.Fragment
+
+	for _, field := range requestQueryParams(req) {
+		options := listOptions{SessionID: sid, FileID: fid, Query: paramFilter(field.Name, field.Value), Size: defaultPageSize}
+		parts.Params = append(parts.Params, queryParam{Name: field.Name, Value: field.Value, FilterURL: options.url(nil)})
+	}
+	return parts
+}
+
+// 条目详情中的URL分解部分
+var entryURLTemplate = `{{with .URLParts}}
+    <h4>URL 分解</h4>
+    {{if .Error}}
+    <p class="error-message">解析URL失败: {{.Error}}</p>
+    {{else}}
+    <table class="param-table">
+        <tr><th>协议</th><td>{{.Scheme}}</td></tr>
+        <tr><th>主机</th><td>{{.Host}}</td></tr>
+        <tr><th>端口</th><td>{{.Port}}{{if .DefaultPort}}（默认）{{end}}</td></tr>
+        <tr><th>路径</th><td>{{.Path}}</td></tr>
+        {{if .Segments}}<tr><th>路径段</th><td>{{range $i, $s := .Segments}}<span class="path-segment" title="第 {{$i}} 段">{{$s}}</span>{{end}}</td></tr>{{end}}
+        {{if .Fragment}}<tr><th>锚点</th><td>{{.Fragment}}</td></tr>{{end}}
+    </table>
+    {{if .Params}}
+    <h4>查询参数 ({{len .Params}})</h4>
+    <table class="param-table">
+        <tr><th>名称</th><th>值（已解码）</th><th></th></tr>
+        {{range .Params}}<tr><td>{{.Name}}</td><td class="param-value">{{.Value}}</td><td><a href="{{.FilterURL}}">过滤相同参数</a></td></tr>{{end}}
+    </table>
+    {{end}}
+    {{end}}
+{{end}}`
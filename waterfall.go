@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 瀑布图中的一个阶段
+type waterfallPhase struct {
+	Name     string
+	Class    string
+	Width    float64
+	Duration float64
+}
+
+// 瀑布图中的一行，位置和宽度均为占整个时间轴的百分比
+type waterfallRow struct {
+	Offset float64
+	Width  float64
+	Phases []waterfallPhase
+	Title  string
+}
+
+// 页面事件标记线
+type waterfallMarker struct {
+	Name     string
+	Class    string
+	Position float64
+}
+
+// 当前页条目的瀑布图数据，Rows 按条目序号索引
+type waterfall struct {
+	Rows    map[int]waterfallRow
+	Markers []waterfallMarker
+	Total   float64
+}
+
+// 整个HAR文件的时间轴，起点为最早开始的请求或页面，与分页无关，每个文件只计算一次
+type waterfallScale struct {
+	first   time.Time
+	total   float64
+	markers []waterfallMarker
+}
+
+// 解析HAR中的时间
+func parseHARTime(value string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// 按开始时间排序条目序号，时间只解析一次，不同时区的时间也能正确比较，相同时保持原来的顺序
+func sortByStartTime(entries []Entry, indexes []int) {
+	times := make([]time.Time, len(entries))
+	for _, index := range indexes {
+		times[index], _ = parseHARTime(entries[index].StartedDateTime)
+	}
+	sort.SliceStable(indexes, func(i, j int) bool { return times[indexes[i]].Before(times[indexes[j]]) })
+}
+
+// 拆分请求各阶段耗时，connect 中包含的 ssl 时间单独列出
+func entryPhases(timings Timings) []waterfallPhase {
+	connect := timings.Connect
+	if connect > 0 && timings.SSL > 0 {
+		connect -= timings.SSL
+	}
+
+	phases := []waterfallPhase{
+		{Name: "blocked", Class: "phase-blocked", Duration: timings.Blocked},
+		{Name: "dns", Class: "phase-dns", Duration: timings.DNS},
+		{Name: "connect", Class: "phase-connect", Duration: connect},
+		{Name: "ssl", Class: "phase-ssl", Duration: timings.SSL},
+		{Name: "send", Class: "phase-send", Duration: timings.Send},
+		{Name: "wait", Class: "phase-wait", Duration: timings.Wait},
+		{Name: "receive", Class: "phase-receive", Duration: timings.Receive},
+	}
+
+	var result []waterfallPhase
+	for _, phase := range phases {
+		if phase.Duration > 0 {
+			result = append(result, phase)
+		}
+	}
+	return result
+}
+
+// 获取文件的瀑布图时间轴，第一次使用时计算
+func (f *harFile) waterfallScale() *waterfallScale {
+	f.scaleOnce.Do(func() {
+		f.scale = newWaterfallScale(f.HAR)
+	})
+	return f.scale
+}
+
+// 请求开始时间相对时间轴起点的毫秒数
+func (s *waterfallScale) offsetOf(t time.Time) float64 {
+	if t.IsZero() || s.first.IsZero() {
+		return 0
+	}
+	return float64(t.Sub(s.first)) / float64(time.Millisecond)
+}
+
+// 根据所有条目和页面计算时间轴的起点、总长度和页面事件标记
+func newWaterfallScale(harData *HAR) *waterfallScale {
+	entries := harData.Log.Entries
+	starts := make([]time.Time, len(entries))
+	scale := &waterfallScale{}
+
+	// 找到最早开始的请求或页面作为时间轴起点
+	for i, entry := range entries {
+		if t, ok := parseHARTime(entry.StartedDateTime); ok {
+			starts[i] = t
+			if scale.first.IsZero() || t.Before(scale.first) {
+				scale.first = t
+			}
+		}
+	}
+	for _, page := range harData.Log.Pages {
+		if t, ok := parseHARTime(page.StartTime); ok && (scale.first.IsZero() || t.Before(scale.first)) {
+			scale.first = t
+		}
+	}
+
+	// 计算时间轴总长度
+	for i, entry := range entries {
+		if end := scale.offsetOf(starts[i]) + entry.Time; end > scale.total {
+			scale.total = end
+		}
+	}
+	for _, page := range harData.Log.Pages {
+		start, ok := parseHARTime(page.StartTime)
+		if !ok {
+			continue
+		}
+		if end := scale.offsetOf(start) + page.PageTimings.OnLoad; end > scale.total {
+			scale.total = end
+		}
+	}
+	if scale.total <= 0 {
+		scale.total = 1
+	}
+
+	for _, page := range harData.Log.Pages {
+		start, ok := parseHARTime(page.StartTime)
+		if !ok {
+			continue
+		}
+		if page.PageTimings.OnContentLoad >= 0 {
+			scale.markers = append(scale.markers, waterfallMarker{
+				Name:     fmt.Sprintf("%s DOMContentLoaded: %.0f ms", page.Title, page.PageTimings.OnContentLoad),
+				Class:    "marker-content-load",
+				Position: (scale.offsetOf(start) + page.PageTimings.OnContentLoad) / scale.total * 100,
+			})
+		}
+		if page.PageTimings.OnLoad >= 0 {
+			scale.markers = append(scale.markers, waterfallMarker{
+				Name:     fmt.Sprintf("%s Load: %.0f ms", page.Title, page.PageTimings.OnLoad),
+				Class:    "marker-load",
+				Position: (scale.offsetOf(start) + page.PageTimings.OnLoad) / scale.total * 100,
+			})
+		}
+	}
+	return scale
+}
+
+// 根据 startedDateTime 和 timings 构建瀑布图，只生成 indexes 中条目的行
+func buildWaterfall(scale *waterfallScale, entries []Entry, indexes []int) *waterfall {
+	result := &waterfall{Rows: make(map[int]waterfallRow, len(indexes)), Markers: scale.markers, Total: scale.total}
+	for _, i := range indexes {
+		entry := &entries[i]
+		start, _ := parseHARTime(entry.StartedDateTime)
+		offset := scale.offsetOf(start)
+		row := waterfallRow{
+			Offset: offset / scale.total * 100,
+			Width:  entry.Time / scale.total * 100,
+			Phases: entryPhases(entry.Timings),
+		}
+
+		phaseTotal := 0.0
+		for _, phase := range row.Phases {
+			phaseTotal += phase.Duration
+		}
+		if phaseTotal <= 0 {
+			row.Phases = []waterfallPhase{{Name: "total", Class: "phase-wait", Duration: entry.Time}}
+			phaseTotal = entry.Time
+		}
+
+		// 阶段宽度为占本行宽度的百分比
+		var lines []string
+		lines = append(lines, fmt.Sprintf("开始: +%.2f ms", offset))
+		for j := range row.Phases {
+			if phaseTotal > 0 {
+				row.Phases[j].Width = row.Phases[j].Duration / phaseTotal * 100
+			}
+			lines = append(lines, fmt.Sprintf("%s: %.2f ms", row.Phases[j].Name, row.Phases[j].Duration))
+		}
+		row.Title = strings.Join(lines, "\n")
+		result.Rows[i] = row
+	}
+	return result
+}
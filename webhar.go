@@ -6,63 +6,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
-	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
-
-	"golang.org/x/text/encoding/simplifiedchinese"
-	"golang.org/x/text/transform"
+	"sync"
+	"time"
 )
 
-// 定义HAR文件结构体
-type HAR struct {
-	Log Log `json:"log"`
-}
-
-type Log struct {
-	Version string  `json:"version"`
-	Creator Creator `json:"creator"`
-	Pages   []Page  `json:"pages,omitempty"`
-	Entries []Entry `json:"entries"`
-}
-
-type Creator struct {
-	Name    string `json:"name"`
-	Version string `json:"version"`
-}
-
-type Page struct {
-	ID        string `json:"id"`
-	Title     string `json:"title"`
-	StartTime string `json:"startedDateTime"`
-}
-
-type Entry struct {
-	Request  Request  `json:"request"`
-	Response Response `json:"response"`
-	Time     float64  `json:"time"`
-}
-
-type Request struct {
-	Method  string   `json:"method"`
-	URL     string   `json:"url"`
-	Headers []Header `json:"headers"`
-}
-
-type Response struct {
-	Status     int      `json:"status"`
-	StatusText string   `json:"statusText"`
-	Headers    []Header `json:"headers"`
-}
-
-type Header struct {
-	Name  string `json:"name"`
-	Value string `json:"value"`
-}
-
-// 全局变量，用于存储当前解析的HAR数据
-var currentHARData *HAR
+// 正在进行的上传解析进度，键为页面生成的进度ID
+var (
+	uploadProgressMu sync.Mutex
+	uploadProgresses = make(map[string]*loadProgress)
+)
 
 // 从URL中提取域名
 func extractDomain(urlStr string) string {
@@ -87,6 +45,7 @@ func extractUniqueDomains(harData *HAR) []string {
 	for domain := range domainMap {
 		domains = append(domains, domain)
 	}
+	sort.Strings(domains)
 	return domains
 }
 
@@ -110,26 +69,96 @@ func formatFileSize(size int) string {
 	}
 }
 
-// 生成CSV内容（GBK编码）
-func generateCSV(domains []string) []byte {
+// 单个域名的请求统计
+type domainStats struct {
+	Domain    string
+	Count     int
+	Bytes     int64
+	Time      float64
+	Statuses  map[string]int // 按状态码分类计数，如 2xx、4xx、failed
+	FirstSeen string
+	LastSeen  string
+	first     time.Time
+	last      time.Time
+}
+
+// 按域名统计请求数量、响应大小、耗时、状态码分布和首次/最后出现时间
+func collectDomainStats(harData *HAR) []*domainStats {
+	statsMap := make(map[string]*domainStats)
+	for _, entry := range harData.Log.Entries {
+		domain := extractDomain(entry.Request.URL)
+		if domain == "" {
+			continue
+		}
+		stats, ok := statsMap[domain]
+		if !ok {
+			stats = &domainStats{Domain: domain, Statuses: make(map[string]int)}
+			statsMap[domain] = stats
+		}
+		stats.Count++
+		if entry.Response.Content.Size > 0 {
+			stats.Bytes += entry.Response.Content.Size
+		}
+		if entry.Time > 0 {
+			stats.Time += entry.Time
+		}
+		stats.Statuses[statusClass(entry.Response.Status)]++
+		if started, ok := parseHARTime(entry.StartedDateTime); ok {
+			if stats.first.IsZero() || started.Before(stats.first) {
+				stats.first, stats.FirstSeen = started, entry.StartedDateTime
+			}
+			if stats.last.IsZero() || started.After(stats.last) {
+				stats.last, stats.LastSeen = started, entry.StartedDateTime
+			}
+		}
+	}
+
+	domains := make([]*domainStats, 0, len(statsMap))
+	for _, stats := range statsMap {
+		domains = append(domains, stats)
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Domain < domains[j].Domain })
+	return domains
+}
+
+// 状态码分布文本，如 "2xx:10 4xx:2"
+func (s *domainStats) statusText() string {
+	classes := make([]string, 0, len(s.Statuses))
+	for class := range s.Statuses {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	parts := make([]string, len(classes))
+	for i, class := range classes {
+		parts[i] = fmt.Sprintf("%s:%d", class, s.Statuses[class])
+	}
+	return strings.Join(parts, " ")
+}
+
+// 生成域名CSV内容，返回转换后的内容和字符集名称
+func generateCSV(domains []*domainStats, encoding string) ([]byte, string) {
 	var buf bytes.Buffer
 	writer := csv.NewWriter(&buf)
 
 	// 写入标题行
-	writer.Write([]string{"域名"})
+	writer.Write([]string{"域名", "请求数", "总大小(byte)", "总耗时(ms)", "状态码分布", "首次出现", "最后出现"})
 
 	// 写入域名数据
-	for _, domain := range domains {
-		writer.Write([]string{domain})
+	for _, stats := range domains {
+		writer.Write([]string{
+			stats.Domain,
+			strconv.Itoa(stats.Count),
+			strconv.FormatInt(stats.Bytes, 10),
+			strconv.FormatFloat(stats.Time, 'f', 2, 64),
+			stats.statusText(),
+			stats.FirstSeen,
+			stats.LastSeen,
+		})
 	}
 
 	writer.Flush()
 
-	// 将UTF-8转换为GBK
-	encoder := simplifiedchinese.GBK.NewEncoder()
-	gbkBuf, _, _ := transform.Bytes(encoder, buf.Bytes())
-
-	return gbkBuf
+	return encodeCSV(buf.Bytes(), encoding)
 }
 
 var htmlTemplate = `<!DOCTYPE html>
@@ -197,6 +226,103 @@ var htmlTemplate = `<!DOCTYPE html>
         .status-500 {
             color: darkred;
         }
+        /* 工作区布局 */
+        .workspace {
+            display: flex;
+            align-items: flex-start;
+        }
+        .workspace-main {
+            flex: 1;
+            min-width: 0;
+        }
+        .file-sidebar {
+            width: 220px;
+            flex-shrink: 0;
+            margin-right: 20px;
+            background-color: #f0f0f0;
+            border-radius: 5px;
+            padding: 10px;
+        }
+        .file-sidebar h3 {
+            margin-top: 0;
+        }
+        .file-list {
+            list-style-type: none;
+            padding: 0;
+            margin: 0;
+        }
+        .file-list li {
+            position: relative;
+            padding: 6px 24px 6px 8px;
+            border-radius: 4px;
+            margin-bottom: 4px;
+        }
+        .file-list li.active {
+            background-color: #2196F3;
+        }
+        .file-list li.active a,
+        .file-list li.active .file-count {
+            color: white;
+        }
+        .file-link {
+            display: block;
+            color: #333;
+            text-decoration: none;
+            white-space: nowrap;
+            overflow: hidden;
+            text-overflow: ellipsis;
+        }
+        .file-close {
+            position: absolute;
+            right: 8px;
+            top: 4px;
+            color: #999;
+            text-decoration: none;
+            font-size: 16px;
+        }
+        .file-close:hover {
+            color: #f44336;
+        }
+        .file-count {
+            font-size: 12px;
+            color: #666;
+        }
+        
+        /* 过滤栏样式 */
+        .filter-bar {
+            display: flex;
+            flex-wrap: wrap;
+            align-items: center;
+            margin: 10px 3%;
+        }
+        .filter-input {
+            flex: 1;
+            min-width: 300px;
+            padding: 8px 12px;
+            border: 1px solid #ddd;
+            border-radius: 4px;
+            font-size: 14px;
+        }
+        .filter-bar .btn {
+            margin-top: 5px;
+        }
+        .filter-result {
+            font-size: 13px;
+            color: #666;
+            margin-left: 5px;
+        }
+        .filter-help {
+            width: 100%;
+            font-size: 13px;
+            color: #555;
+        }
+        .filter-help pre {
+            background-color: #f9f9f9;
+            padding: 8px;
+            border-radius: 4px;
+            white-space: pre-wrap;
+        }
+        
         /* 统一按钮样式 */
         .btn {
             padding: 8px 16px;
@@ -363,19 +489,53 @@ var htmlTemplate = `<!DOCTYPE html>
             margin: 0;
         }
         
-        .progress-container {
+        /* 瀑布图样式 */
+        .waterfall {
+            position: relative;
             width: 100%;
             background-color: #f0f0f0;
-            border-radius: 5px;
+            border-radius: 3px;
             margin: 5px 0;
             height: 10px;
         }
-        .progress-bar {
+        .waterfall-bar {
+            position: absolute;
+            top: 0;
             height: 100%;
-            background-color: #4CAF50;
-            border-radius: 5px;
-            width: 0%;
+            min-width: 2px;
+            display: flex;
         }
+        .waterfall-phase {
+            height: 100%;
+        }
+        .waterfall-marker {
+            position: absolute;
+            top: -2px;
+            bottom: -2px;
+            width: 2px;
+            z-index: 1;
+        }
+        .waterfall-legend span {
+            display: inline-block;
+            margin-right: 12px;
+            font-size: 12px;
+        }
+        .waterfall-legend i {
+            display: inline-block;
+            width: 12px;
+            height: 10px;
+            margin-right: 4px;
+            vertical-align: middle;
+        }
+        .phase-blocked { background-color: #bdbdbd; }
+        .phase-dns { background-color: #009688; }
+        .phase-connect { background-color: #ff9800; }
+        .phase-ssl { background-color: #9c27b0; }
+        .phase-send { background-color: #2196F3; }
+        .phase-wait { background-color: #4CAF50; }
+        .phase-receive { background-color: #03a9f4; }
+        .marker-content-load { background-color: #3f51b5; }
+        .marker-load { background-color: #f44336; }
         .time-text {
             font-size: 12px;
             color: #666;
@@ -421,14 +581,347 @@ var htmlTemplate = `<!DOCTYPE html>
             word-wrap: break-word;
             word-break: break-all;
         }
+        .body-meta {
+            color: #555;
+            font-size: 13px;
+        }
+        .body-tabs .btn {
+            background-color: #f2f2f2;
+            color: #333;
+            padding: 4px 10px;
+            font-size: 12px;
+        }
+        .body-tabs .btn.active {
+            background-color: #2196F3;
+            color: white;
+        }
+        .body-pane {
+            background-color: white;
+            border: 1px solid #ddd;
+            border-radius: 4px;
+            padding: 10px;
+            max-height: 500px;
+            overflow: auto;
+            white-space: pre-wrap;
+            font-family: Consolas, monospace;
+            font-size: 12px;
+        }
+        div.body-pane {
+            white-space: normal;
+            font-family: inherit;
+        }
+        .snippet-btn {
+            background-color: #f2f2f2;
+            color: #333;
+            padding: 4px 10px;
+            font-size: 12px;
+        }
+        .snippet-btn.active {
+            background-color: #2196F3;
+            color: white;
+        }
+        .snippet-status {
+            font-size: 12px;
+            color: #666;
+        }
+        .snippet-text {
+            width: 100%;
+            height: 200px;
+            box-sizing: border-box;
+            font-family: Consolas, monospace;
+            font-size: 12px;
+        }
+        .replay-target {
+            width: 400px;
+            max-width: 100%;
+            padding: 6px 10px;
+            border: 1px solid #ddd;
+            border-radius: 4px;
+        }
+        .replay-result {
+            display: flex;
+            gap: 10px;
+        }
+        .replay-column {
+            flex: 1;
+            min-width: 0;
+            background-color: #f5f5f5;
+            border-radius: 4px;
+            padding: 10px;
+        }
+        .param-table {
+            border-collapse: collapse;
+            width: 100%;
+        }
+        .param-table th,
+        .param-table td {
+            border: 1px solid #ddd;
+            padding: 4px 8px;
+            text-align: left;
+            vertical-align: top;
+        }
+        .param-table th {
+            background-color: #f2f2f2;
+        }
+        .param-value {
+            word-break: break-all;
+        }
+        .path-segment {
+            display: inline-block;
+            background-color: #f2f2f2;
+            border: 1px solid #ddd;
+            border-radius: 3px;
+            padding: 0 6px;
+            margin: 1px 4px 1px 0;
+        }
+        .multipart-part {
+            border-bottom: 1px dashed #ccc;
+            padding: 5px 0;
+        }
+        .multipart-part pre {
+            white-space: pre-wrap;
+            margin: 5px 0;
+        }
+        .body-pane img {
+            max-width: 100%;
+        }
+        .hex-pane {
+            white-space: pre;
+            word-break: normal;
+        }
         .sort-indicator {
-            cursor: pointer;
             margin-left: 5px;
             font-size: 12px;
         }
-        .entries-table th {
+        .entries-table th a {
+            color: #333;
+            text-decoration: none;
+            margin-right: 10px;
+        }
+        
+        /* 导出面板样式 */
+        .export-panel {
+            margin: 10px 3%;
+            font-size: 14px;
+        }
+        .export-panel label {
+            display: inline-block;
+            margin: 5px 10px 5px 0;
+        }
+        .export-panel input[type="text"] {
+            padding: 4px 8px;
+            border: 1px solid #ddd;
+            border-radius: 4px;
+        }
+        .export-note {
+            color: #888;
+            font-size: 12px;
+        }
+        
+        /* Mock服务样式 */
+        .mock-panel label {
+            display: inline-block;
+            margin: 5px 10px 5px 0;
+        }
+        .mock-port {
+            width: 60px;
+            padding: 4px 8px;
+            border: 1px solid #ddd;
+            border-radius: 4px;
+        }
+        .mock-item {
+            background-color: white;
+            border-radius: 4px;
+            padding: 5px 10px;
+            margin: 5px 0;
+        }
+        
+        /* 性能概览样式 */
+        .stats-cards {
+            display: flex;
+            flex-wrap: wrap;
+            gap: 10px;
+            margin: 10px 0;
+        }
+        .stats-card {
+            background-color: white;
+            border-radius: 4px;
+            padding: 10px 15px;
+            min-width: 120px;
+        }
+        .stats-card span {
+            display: block;
+            font-size: 12px;
+            color: #666;
+        }
+        .stats-card strong {
+            font-size: 18px;
+        }
+        .stats-chart {
+            width: 100%;
+            table-layout: fixed;
+            border-collapse: collapse;
+            font-size: 13px;
+        }
+        .stats-chart th {
+            width: 30%;
+            text-align: left;
+            font-weight: normal;
+            overflow: hidden;
+            text-overflow: ellipsis;
+            white-space: nowrap;
+        }
+        .stats-chart td {
+            padding: 2px 5px;
+        }
+        .stats-chart td:last-child {
+            width: 35%;
+            color: #555;
+        }
+        .stats-bar {
+            background-color: #eee;
+            height: 8px;
+            margin: 2px 0;
+        }
+        .stats-bar span {
+            display: block;
+            height: 100%;
+            background-color: #4CAF50;
+        }
+        .stats-bar-bytes span {
+            background-color: #2196F3;
+        }
+        .stats-bar-slow span {
+            background-color: #f44336;
+        }
+        
+        /* Cookie样式 */
+        .cookie-view {
+            max-height: 600px;
+            overflow: auto;
+            font-size: 13px;
+        }
+        .cookie-value {
+            max-width: 300px;
+            word-break: break-all;
+        }
+        .cookie-issue {
+            color: #c62828;
+        }
+        
+        /* 接口统计样式 */
+        .endpoint-view {
+            max-height: 600px;
+            overflow: auto;
+            font-size: 13px;
+        }
+        .endpoint-name {
+            max-width: 500px;
+            word-break: break-all;
+        }
+        .endpoint-name summary {
+            cursor: pointer;
+        }
+        .endpoint-error {
+            color: #c62828;
+        }
+        .endpoint-statuses {
+            display: flex;
+            width: 120px;
+            height: 8px;
+            background-color: #eee;
+        }
+        .endpoint-statuses span {
+            height: 100%;
+            background-color: #9e9e9e;
+        }
+        .endpoint-statuses .status-2xx {
+            background-color: #4CAF50;
+        }
+        .endpoint-statuses .status-3xx {
+            background-color: #2196F3;
+        }
+        .endpoint-statuses .status-4xx {
+            background-color: #ff9800;
+        }
+        .endpoint-statuses .status-5xx, .endpoint-statuses .status-failed {
+            background-color: #f44336;
+        }
+        
+        /* 文件对比样式 */
+        .compare-panel label {
+            display: inline-block;
+            margin: 5px 10px 5px 0;
+        }
+        .compare-result {
+            font-size: 13px;
+        }
+        .compare-result details {
+            margin: 10px 0;
+        }
+        .compare-regression {
+            background-color: #fdecea;
+            color: #c62828;
+        }
+        
+        /* 脱敏面板样式 */
+        .sanitize-panel label {
+            display: inline-block;
+            margin: 5px 10px 5px 0;
+        }
+        .sanitize-custom {
+            width: 100%;
+            box-sizing: border-box;
+            font-family: monospace;
+            font-size: 13px;
+            margin: 5px 0;
+        }
+        .sanitize-preview {
+            max-height: 400px;
+            overflow: auto;
+            font-size: 13px;
+        }
+        
+        /* 页面分组样式 */
+        .page-selector {
+            margin: 10px 3%;
+            font-size: 14px;
+        }
+        .page-selector select {
+            padding: 4px 8px;
+            margin-right: 10px;
+        }
+        .page-group {
+            background-color: #e8eaf6;
             cursor: pointer;
         }
+        .page-group td {
+            padding: 8px 10px;
+        }
+        .page-group-toggle {
+            display: inline-block;
+            width: 16px;
+            color: #3f51b5;
+        }
+        
+        /* 分页样式 */
+        .pager {
+            display: flex;
+            flex-wrap: wrap;
+            align-items: center;
+            margin: 10px 3%;
+            font-size: 14px;
+        }
+        .pager a,
+        .pager span {
+            margin-right: 10px;
+        }
+        .pager .disabled {
+            color: #aaa;
+        }
+        .pager select {
+            padding: 4px;
+        }
         .entries-table th {
             background-color: #f2f2f2;
             font-weight: bold;
@@ -497,6 +990,16 @@ var htmlTemplate = `<!DOCTYPE html>
                 margin: 5px 0;
             }
             
+            .workspace {
+                flex-direction: column;
+            }
+            
+            .file-sidebar {
+                width: auto;
+                margin: 0 0 10px 0;
+                align-self: stretch;
+            }
+            
             .har-info {
                 padding: 10px;
             }
@@ -534,41 +1037,220 @@ var htmlTemplate = `<!DOCTYPE html>
     <h1>HAR Viewer</h1>
     
     <div class="file-upload" style="margin: 20px 0;">
-        <form action="/upload" method="post" enctype="multipart/form-data" style="display: flex; flex-wrap: wrap; align-items: center;" onsubmit="showLoadingMask()">
-            <input type="file" name="harfile" accept=".har" class="file-input" style="margin: 5px;">
+        <form action="/upload?sid={{.SessionID}}" method="post" enctype="multipart/form-data" style="display: flex; flex-wrap: wrap; align-items: center;" onsubmit="showLoadingMask(this)">
+            <input type="file" name="harfile" accept="{{.CaptureAccept}}" title="支持 HAR、Charles、Fiddler SAZ、mitmproxy、Chrome NetLog、pcap" class="file-input" style="margin: 5px;">
             <input type="submit" value="上传HAR文件" class="btn upload-btn">
-            <button type="button" onclick="location.href='/reload'" class="btn reload-btn">重新加载</button>
+            <button type="button" onclick="location.href='/reload?sid={{.SessionID}}'" class="btn reload-btn">重新加载</button>
         </form>
     </div>
+    {{if .UploadError}}
+    <div id="error-modal">
+        <div class="modal-content">
+            <p class="error-message">{{.UploadError}}</p>
+        </div>
+    </div>
+    {{end}}
     
+    <div class="workspace">
+    <aside class="file-sidebar">
+        <h3>已加载文件</h3>
+        {{if .Files}}
+        <ul class="file-list">
+            {{range .Files}}
+            <li class="{{if eq .ID $.FileID}}active{{end}}">
+                <a href="/?sid={{$.SessionID}}&fid={{.ID}}" class="file-link" title="{{.Name}}">{{.Name}}</a>
+                <a href="/close?sid={{$.SessionID}}&fid={{.ID}}" class="file-close" title="关闭文件">×</a>
+                <div class="file-count">{{len .HAR.Log.Entries}} 个请求</div>
+            </li>
+            {{end}}
+        </ul>
+        {{else}}
+        <p class="file-count">暂无文件，请先上传</p>
+        {{end}}
+    </aside>
+    
+    <div class="workspace-main">
     {{if .HARData}}
     <div class="har-info">
         <h2>HAR文件信息</h2>
         <p>文件名: {{.FileName}}</p>
         <p>文件大小: {{.FileSize}} bytes</p>
         <p>请求数量: {{.MethodCountText}}</p>
-        <a href="/download-csv" class="btn download-btn">下载域名CSV文件</a>
+        <form method="get" action="/download-csv">
+            <input type="hidden" name="sid" value="{{.SessionID}}">
+            <input type="hidden" name="fid" value="{{.FileID}}">
+            <input type="submit" value="下载域名CSV文件" class="btn download-btn">
+            <label>编码 {{template "csv-encoding"}}</label>
+        </form>
+        <details class="mock-panel"{{if .Mocks}} open{{end}}>
+            <summary>Mock服务</summary>
+            <form method="post" action="/mock/start?sid={{.SessionID}}&fid={{.FileID}}">
+                <label>端口 <input type="text" name="port" value="9000" class="mock-port"></label>
+                <label><input type="checkbox" name="ignoreQuery" value="1">不比较查询参数</label>
+                <label><input type="checkbox" name="matchHost" value="1">比较主机名</label>
+                <label><input type="checkbox" name="matchBody" value="1">比较请求体</label>
+                <input type="submit" value="启动Mock服务" class="btn upload-btn">
+            </form>
+            {{range .Mocks}}
+            <div class="mock-item">
+                <form method="post" action="/mock/stop?sid={{$.SessionID}}&fid={{$.FileID}}">
+                    <input type="hidden" name="id" value="{{.ID}}">
+                    <a href="http://localhost:{{.Port}}/" target="_blank">http://localhost:{{.Port}}</a>
+                    <span class="file-count">已匹配 {{.Matched}} 次，未匹配 {{len .Unmatched}} 个请求</span>
+                    <input type="submit" value="停止" class="btn reload-btn">
+                    <a href="/?sid={{$.SessionID}}&fid={{$.FileID}}" class="btn download-btn">刷新</a>
+                </form>
+                {{if .Unmatched}}
+                <table class="param-table">
+                    <tr><th>次数</th><th>方法</th><th>URL</th><th>最后请求时间</th></tr>
+                    {{range .Unmatched}}<tr><td>{{.Count}}</td><td>{{.Method}}</td><td>{{.URL}}</td><td>{{.Last.Format "15:04:05"}}</td></tr>{{end}}
+                </table>
+                {{end}}
+            </div>
+            {{end}}
+        </details>
+        <details class="stats-panel" ontoggle="if (this.open) loadLazySections(this)">
+            <summary>性能概览</summary>
+            <div class="lazy-section" data-src="/stats?sid={{.SessionID}}&fid={{.FileID}}">加载中...</div>
+        </details>
+        <details class="cookie-panel" ontoggle="if (this.open) loadLazySections(this)">
+            <summary>Cookie</summary>
+            <div class="lazy-section" data-src="/cookies?sid={{.SessionID}}&fid={{.FileID}}">加载中...</div>
+        </details>
+        <details class="endpoint-panel" ontoggle="if (this.open) loadLazySections(this)">
+            <summary>接口统计</summary>
+            <div class="lazy-section" data-src="/endpoints?sid={{.SessionID}}&fid={{.FileID}}&q={{.Query}}">加载中...</div>
+        </details>
+        {{if gt (len .Files) 1}}
+        <details class="compare-panel">
+            <summary>对比文件</summary>
+            <form method="get" action="/compare" onsubmit="compareFiles(this); return false;">
+                <input type="hidden" name="sid" value="{{.SessionID}}">
+                <input type="hidden" name="fid" value="{{.FileID}}">
+                <label>将 {{.FileName}} 与
+                    <select name="target">
+                        {{range .Files}}{{if ne .ID $.FileID}}<option value="{{.ID}}">{{.Name}}</option>{{end}}{{end}}
+                    </select>
+                    对比
+                </label>
+                <label><input type="checkbox" name="ignoreQueryValues" value="1">只比较查询参数名</label>
+                <label>耗时增长阈值 <input type="text" name="threshold" value="20" class="mock-port">%</label>
+                <input type="submit" value="对比" class="btn upload-btn">
+                <div class="compare-output"></div>
+            </form>
+        </details>
+        {{end}}
+        <details class="sanitize-panel">
+            <summary>脱敏导出</summary>
+            <form method="post" action="/sanitize/download?sid={{.SessionID}}&fid={{.FileID}}">
+                <div>
+                    {{range .RedactRules}}<label><input type="checkbox" name="rule" value="{{.Key}}" checked>{{.Name}}</label>{{end}}
+                </div>
+                <textarea name="custom" class="sanitize-custom" rows="3" placeholder="自定义正则表达式，每行一个，包含分组时只替换第一个分组，例如 orderId=(\d+)"></textarea>
+                <div>
+                    <button type="button" class="btn upload-btn" onclick="previewSanitize(this.form)">预览</button>
+                    <input type="submit" value="下载脱敏HAR" class="btn download-btn">
+                </div>
+                <div class="sanitize-output"></div>
+            </form>
+        </details>
     </div>
     
     <h2>请求列表</h2>
+    <form class="filter-bar" method="get" action="/">
+        <input type="hidden" name="sid" value="{{.SessionID}}">
+        <input type="hidden" name="fid" value="{{.FileID}}">
+        {{if .Options.Sort}}<input type="hidden" name="sort" value="{{.Options.Sort}}"><input type="hidden" name="order" value="{{.Options.Order}}">{{end}}
+        <input type="hidden" name="size" value="{{.Options.Size}}">
+        {{if .Options.Group}}<input type="hidden" name="group" value="page">{{end}}
+        <input type="text" name="q" value="{{.Query}}" class="filter-input" placeholder="过滤，例如: method:POST status:4xx domain:example.com time:>500 body:token">
+        <input type="submit" value="过滤" class="btn upload-btn">
+        {{if .Query}}<a href="/?sid={{.SessionID}}&fid={{.FileID}}" class="btn download-btn">清除</a>{{end}}
+        <span class="filter-result">{{if .FilterError}}<span class="error-message">{{.FilterError}}</span>{{else}}匹配 {{.Pagination.Total}} / {{len .HARData.Log.Entries}} 个请求{{end}}</span>
+        <details class="filter-help">
+            <summary>过滤语法</summary>
+            <pre>{{.FilterHelp}}</pre>
+        </details>
+    </form>
+    <details class="export-panel">
+        <summary>导出当前过滤结果</summary>
+        <form method="get" action="/export">
+            <input type="hidden" name="sid" value="{{.SessionID}}">
+            <input type="hidden" name="fid" value="{{.FileID}}">
+            <input type="hidden" name="q" value="{{.Query}}">
+            {{if .Options.Sort}}<input type="hidden" name="sort" value="{{.Options.Sort}}"><input type="hidden" name="order" value="{{.Options.Order}}">{{end}}
+            <div>
+                {{range .ExportColumns}}<label><input type="checkbox" name="column" value="{{.Key}}"{{if .Default}} checked{{end}}>{{.Title}}</label>{{end}}
+            </div>
+            <div>
+                <label>请求头 <input type="text" name="requestHeaders" placeholder="如 User-Agent,Referer"></label>
+                <label>响应头 <input type="text" name="responseHeaders" placeholder="如 Content-Type,Server"></label>
+            </div>
+            <div>
+                <label>格式 <select name="format">
+                    <option value="csv">CSV</option><option value="xlsx">XLSX</option>
+                    {{range .DataExportFormats}}<option value="{{.Key}}">{{.Name}}</option>{{end}}
+                </select></label>
+                <label>CSV编码 {{template "csv-encoding"}}</label>
+                <span class="export-note">列选择只用于 CSV 和 XLSX，其他格式包含完整的请求和响应</span>
+                <input type="submit" value="导出" class="btn download-btn">
+            </div>
+        </form>
+    </details>
+    {{if .PageOptions}}
+    <div class="page-selector">
+        页面
+        <select onchange="location.href = this.value">
+            {{range .PageOptions}}<option value="{{.URL}}"{{if .Selected}} selected{{end}}>{{.Title}}</option>{{end}}
+        </select>
+        <a href="{{.GroupURL}}" class="btn download-btn">{{if .Options.Group}}取消分组{{else}}按页面分组{{end}}</a>
+    </div>
+    {{end}}
+    {{template "pager" .Pagination}}
+    <div class="waterfall-legend">
+        <span><i class="phase-blocked"></i>排队</span>
+        <span><i class="phase-dns"></i>DNS</span>
+        <span><i class="phase-connect"></i>连接</span>
+        <span><i class="phase-ssl"></i>SSL</span>
+        <span><i class="phase-send"></i>发送</span>
+        <span><i class="phase-wait"></i>等待</span>
+        <span><i class="phase-receive"></i>接收</span>
+        <span><i class="marker-content-load"></i>DOMContentLoaded</span>
+        <span><i class="marker-load"></i>Load</span>
+    </div>
     <div class="table-container">
         <table class="entries-table" id="entries-table">
             <thead>
                 <tr>
-                    <th class="method-col" onclick="sortEntries('method')">
-                        方法 <span class="sort-indicator" id="sort-method">↕</span>
+                    <th class="method-col">
+                        <a href="{{index .SortLinks "method"}}">方法 <span class="sort-indicator">{{index .SortMarks "method"}}</span></a>
                     </th>
-                    <th class="url-col" onclick="sortEntries('url')">
-                        URL <span class="sort-indicator" id="sort-url">↕</span>
+                    <th class="url-col">
+                        <a href="{{index .SortLinks "url"}}">URL <span class="sort-indicator">{{index .SortMarks "url"}}</span></a>
+                        <a href="{{index .SortLinks "status"}}">状态 <span class="sort-indicator">{{index .SortMarks "status"}}</span></a>
+                        <a href="{{index .SortLinks "start"}}">开始时间 <span class="sort-indicator">{{index .SortMarks "start"}}</span></a>
                     </th>
-                    <th class="time-col" onclick="sortEntries('time')">
-                        耗时 <span class="sort-indicator" id="sort-time">↕</span>
+                    <th class="time-col">
+                        <a href="{{index .SortLinks "time"}}">耗时 <span class="sort-indicator">{{index .SortMarks "time"}}</span></a>
                     </th>
                 </tr>
             </thead>
             <tbody id="entries-list">
-                {{range $i, $entry := .HARData.Log.Entries}}
-                <tr class="entry-item" onclick="toggleDetail(this)" data-method="{{$entry.Request.Method}}" data-url="{{$entry.Request.URL}}" data-time="{{$entry.Time}}">
+                {{range .Rows}}
+                {{if .Group}}
+                {{with .Group}}
+                <tr class="page-group" onclick="togglePageGroup(this)">
+                    <td colspan="3">
+                        <span class="page-group-toggle">▼</span>
+                        <strong>{{if .Title}}{{.Title}}{{else}}{{.ID}}{{end}}</strong>
+                        <span class="file-count">{{.Count}} 个请求，{{.Size}} bytes，累计 {{printf "%.2f" .Time}} ms{{if gt .OnLoad 0.0}}，onLoad {{printf "%.0f" .OnLoad}} ms{{end}}</span>
+                    </td>
+                </tr>
+                {{end}}
+                {{else}}
+                {{$i := .Index}}
+                {{$entry := index $.HARData.Log.Entries $i}}
+                <tr class="entry-item" onclick="toggleDetail(this)" data-index="{{$i}}">
                     <td class="method-col">
                         <span class="request-method">{{$entry.Request.Method}}</span>
                     </td>
@@ -577,9 +1259,14 @@ var htmlTemplate = `<!DOCTYPE html>
                             <span class="url-text">{{$entry.Request.URL}}</span>
                             <span class="status-code status-{{$entry.Response.Status}}">{{$entry.Response.Status}} {{$entry.Response.StatusText}}</span>
                         </div>
-                        <div class="progress-container">
-                            <div class="progress-bar" style="width: {{$entry.Time}}%;"></div>
+                        {{with index $.Waterfall.Rows $i}}
+                        <div class="waterfall" title="{{.Title}}">
+                            {{range $.Waterfall.Markers}}<div class="waterfall-marker {{.Class}}" style="left: {{printf "%.3f" .Position}}%;" title="{{.Name}}"></div>{{end}}
+                            <div class="waterfall-bar" style="left: {{printf "%.3f" .Offset}}%; width: {{printf "%.3f" .Width}}%;">
+                                {{range .Phases}}<div class="waterfall-phase {{.Class}}" style="width: {{printf "%.3f" .Width}}%;"></div>{{end}}
+                            </div>
                         </div>
+                        {{end}}
                     </td>
                     <td class="time-col">
                         <span class="time-text">{{printf "%.2f" $entry.Time}} ms</span>
@@ -587,41 +1274,26 @@ var htmlTemplate = `<!DOCTYPE html>
                 </tr>
                 <tr class="entry-detail" style="display: none;">
                     <td colspan="3">
-                        <div style="padding: 15px; background-color: #e0e0e0; border-radius: 5px;">
-                            <h3>请求详情</h3>
-                            <p><strong>URL:</strong> {{$entry.Request.URL}}</p>
-                            <p><strong>方法:</strong> {{$entry.Request.Method}}</p>
-                            <p><strong>状态:</strong> {{$entry.Response.Status}} {{$entry.Response.StatusText}}</p>
-                            <p><strong>耗时:</strong> {{printf "%.2f" $entry.Time}} ms</p>
-                            
-                            <h4>请求头</h4>
-                            <ul>
-                                {{range $header := $entry.Request.Headers}}
-                                <li>{{$header.Name}}: {{$header.Value}}</li>
-                                {{end}}
-                            </ul>
-                            
-                            <h4>响应头</h4>
-                            <ul>
-                                {{range $header := $entry.Response.Headers}}
-                                <li>{{$header.Name}}: {{$header.Value}}</li>
-                                {{end}}
-                            </ul>
-                        </div>
+                        <div class="lazy-section" data-src="/entry-detail?sid={{$.SessionID}}&fid={{$.FileID}}&index={{$i}}">加载中...</div>
                     </td>
                 </tr>
                 {{end}}
+                {{end}}
             </tbody>
         </table>
     </div>
+    {{template "pager" .Pagination}}
     
     {{end}}
+    </div>
+    </div>
     
     <!-- 加载遮罩层 -->
     <div id="loading-mask" style="display: none;">
         <div class="loading-content">
             <div class="loading-spinner"></div>
             <p>文件正在读取，请稍后...</p>
+            <p id="loading-progress"></p>
         </div>
     </div>
     
@@ -629,8 +1301,11 @@ var htmlTemplate = `<!DOCTYPE html>
     <button id="back-to-top" onclick="scrollToTop()" title="返回顶部">↑</button>
     
     <script>
-        // 显示加载遮罩层
-        function showLoadingMask() {
+        // 显示加载遮罩层，并轮询服务端的解析进度
+        function showLoadingMask(form) {
+            const progressId = Date.now().toString(36) + Math.random().toString(36).slice(2);
+            form.action = form.action + '&progress=' + progressId;
+            
             // 300ms后显示遮罩层，避免快速上传时闪烁
             setTimeout(function() {
                 const mask = document.getElementById('loading-mask');
@@ -638,12 +1313,27 @@ var htmlTemplate = `<!DOCTYPE html>
                     mask.style.display = 'flex';
                 }
             }, 300);
+            
+            setInterval(function() {
+                fetch('/progress?id=' + progressId)
+                    .then(function(resp) { return resp.ok ? resp.json() : null; })
+                    .then(function(data) {
+                        if (!data) return;
+                        const text = document.getElementById('loading-progress');
+                        let percent = '';
+                        if (data.total > 0) {
+                            percent = ' (' + Math.min(100, data.read * 100 / data.total).toFixed(1) + '%)';
+                        }
+                        text.textContent = '已读取 ' + (data.read / 1048576).toFixed(1) + ' MB' + percent + '，已解析 ' + data.entries + ' 个请求';
+                    })
+                    .catch(function() {});
+            }, 500);
         }
         
         // 检查URL参数，显示错误弹窗
         function checkError() {
             const urlParams = new URLSearchParams(window.location.search);
-            if (urlParams.get('error') === '1') {
+            if (urlParams.has('error')) {
                 const modal = document.getElementById('error-modal');
                 if (modal) {
                     modal.style.display = 'flex';
@@ -652,7 +1342,8 @@ var htmlTemplate = `<!DOCTYPE html>
                     const timeoutId = setTimeout(function() {
                         modal.style.display = 'none';
                         // 移除URL中的error参数，避免刷新页面后再次显示弹窗
-                        window.history.replaceState({}, document.title, window.location.pathname);
+                        urlParams.delete('error');
+                        window.history.replaceState({}, document.title, window.location.pathname + '?' + urlParams.toString());
                     }, 5000);
                     
                     // 点击页面任一位置关闭弹窗
@@ -660,7 +1351,8 @@ var htmlTemplate = `<!DOCTYPE html>
                         modal.style.display = 'none';
                         clearTimeout(timeoutId);
                         // 移除URL中的error参数，避免刷新页面后再次显示弹窗
-                        window.history.replaceState({}, document.title, window.location.pathname);
+                        urlParams.delete('error');
+                        window.history.replaceState({}, document.title, window.location.pathname + '?' + urlParams.toString());
                         // 移除事件监听器，避免内存泄漏
                         document.removeEventListener('click', closeModal);
                     }
@@ -680,202 +1372,141 @@ var htmlTemplate = `<!DOCTYPE html>
             if (detail && detail.classList.contains('entry-detail')) {
                 if (detail.style.display === 'none') {
                     detail.style.display = 'table-row';
+                    loadLazySections(detail);
                 } else {
                     detail.style.display = 'none';
                 }
             }
         }
         
-        // 获取所有数据行和对应的详情行
-        function getRowPairs() {
-            const list = document.getElementById('entries-list');
-            if (!list) return [];
-            
-            const rowPairs = [];
-            let current = list.firstElementChild;
-            while (current) {
-                if (current.classList.contains('entry-item')) {
-                    var dataRow = current;
-                    var detailRow = current.nextElementSibling;
-                    if (detailRow && detailRow.classList.contains('entry-detail')) {
-                        rowPairs.push({data: dataRow, detail: detailRow});
-                    }
-                    current = detailRow.nextElementSibling;
-                } else {
-                    current = current.nextElementSibling;
+        // 折叠或展开页面分组，折叠时同时隐藏已展开的详情
+        function togglePageGroup(row) {
+            const collapsed = row.classList.toggle('collapsed');
+            row.querySelector('.page-group-toggle').textContent = collapsed ? '▶' : '▼';
+            for (let next = row.nextElementSibling; next && !next.classList.contains('page-group'); next = next.nextElementSibling) {
+                if (next.classList.contains('entry-item')) {
+                    next.style.display = collapsed ? 'none' : '';
+                } else if (collapsed) {
+                    next.style.display = 'none';
                 }
             }
-            return rowPairs;
         }
         
-        // 排序方向状态管理
-        let sortDirections = {
-            method: 'asc',
-            url: 'asc',
-            time: 'asc'
-        };
-        
-        // 排序功能实现
-        function sortEntries(sortBy) {
-            const list = document.getElementById('entries-list');
-            if (!list) return;
-            
-            // 获取所有数据行和对应的详情行
-            const rowPairs = getRowPairs();
-            
-            const direction = sortDirections[sortBy];
-            
-            // 对数据行进行排序
-            for (let i = 0; i < rowPairs.length; i++) {
-                for (let j = i + 1; j < rowPairs.length; j++) {
-                    let a = rowPairs[i].data;
-                    let b = rowPairs[j].data;
-                    let aVal, bVal, result = 0;
-                    
-                    if (sortBy === 'method') {
-                        aVal = a.dataset.method;
-                        bVal = b.dataset.method;
-                        result = aVal.localeCompare(bVal);
-                    } else if (sortBy === 'url') {
-                        aVal = a.dataset.url;
-                        bVal = b.dataset.url;
-                        result = aVal.localeCompare(bVal);
-                    } else if (sortBy === 'time') {
-                        aVal = parseFloat(a.dataset.time);
-                        bVal = parseFloat(b.dataset.time);
-                        result = aVal - bVal;
-                    }
-                    
-                    // 根据排序方向调整结果
-                    if (direction === 'desc') {
-                        result = -result;
-                    }
-                    
-                    // 交换位置
-                    if (result > 0) {
-                        let temp = rowPairs[i];
-                        rowPairs[i] = rowPairs[j];
-                        rowPairs[j] = temp;
-                    }
-                }
-            }
-            
-            // 切换排序方向
-            sortDirections[sortBy] = direction === 'asc' ? 'desc' : 'asc';
-            
-            // 更新表头排序指示器
-            updateSortIndicators();
-            
-            // 清空列表并重新添加排序后的项（包括对应的详情行）
-            list.innerHTML = '';
-            for (let i = 0; i < rowPairs.length; i++) {
-                list.appendChild(rowPairs[i].data);
-                list.appendChild(rowPairs[i].detail);
-            }
+        // 按需加载详情中的内容（如请求体、响应体），只加载一次
+        function loadLazySections(container) {
+            container.querySelectorAll('.lazy-section').forEach(function(section) {
+                if (section.dataset.loaded === '1') return;
+                section.dataset.loaded = '1';
+                fetch(section.dataset.src)
+                    .then(function(resp) { return resp.text(); })
+                    .then(function(html) {
+                        section.innerHTML = html;
+                        // 加载的内容中可能还有需要按需加载的部分
+                        loadLazySections(section);
+                    })
+                    .catch(function() {
+                        section.dataset.loaded = '';
+                        section.textContent = '加载失败';
+                    });
+            });
         }
         
-        // 更新排序指示器
-        function updateSortIndicators() {
-            // 重置所有指示器为默认状态
-            document.getElementById('sort-method').textContent = '↕';
-            document.getElementById('sort-url').textContent = '↕';
-            document.getElementById('sort-time').textContent = '↕';
-            
-            // 对于每个排序字段，更新对应的指示器
-            var fields = ['method', 'url', 'time'];
-            for (var i = 0; i < fields.length; i++) {
-                var field = fields[i];
-                var direction = sortDirections[field];
-                var indicator = document.getElementById('sort-' + field);
-                if (indicator) {
-                    if (direction === 'asc') {
-                        indicator.textContent = '↑';
-                    } else {
-                        indicator.textContent = '↓';
-                    }
-                }
-            }
+        // 使用新的地址重新加载所在的按需加载部分，如切换排序
+        function reloadLazySection(element, src) {
+            const section = element.closest('.lazy-section');
+            section.dataset.src = src;
+            section.dataset.loaded = '';
+            loadLazySections(section.parentNode);
+            return false;
         }
         
-        // 按请求方法排序，将指定方法的请求置顶
-        function sortByMethod(method) {
-            const list = document.getElementById('entries-list');
-            if (!list) return;
-            
-            // 获取所有数据行和对应的详情行
-            const rowPairs = getRowPairs();
-            
-            // 按方法类型排序，将指定方法的请求置顶
-            rowPairs.sort(function(a, b) {
-                var aMethod = a.data.dataset.method;
-                var bMethod = b.data.dataset.method;
-                
-                // 检查a是否匹配指定方法
-                var aMatch = (method === 'OTHER' && aMethod !== 'GET' && aMethod !== 'POST') || aMethod === method;
-                // 检查b是否匹配指定方法
-                var bMatch = (method === 'OTHER' && bMethod !== 'GET' && bMethod !== 'POST') || bMethod === method;
-                
-                // 如果a匹配而b不匹配，a排在前面
-                if (aMatch && !bMatch) {
-                    return -1;
-                }
-                // 如果b匹配而a不匹配，b排在前面
-                if (!aMatch && bMatch) {
-                    return 1;
-                }
-                // 如果都匹配或都不匹配，保持原顺序
-                return 0;
+        // 切换请求体/响应体的显示方式
+        function switchBodyTab(button, tab) {
+            const view = button.closest('.body-view');
+            view.querySelectorAll('.body-tab').forEach(function(b) {
+                b.classList.toggle('active', b === button);
+            });
+            view.querySelectorAll('.body-pane').forEach(function(pane) {
+                pane.style.display = pane.dataset.tab === tab ? '' : 'none';
             });
-            
-            // 清空列表并重新添加排序后的项（包括对应的详情行）
-            list.innerHTML = '';
-            for (var i = 0; i < rowPairs.length; i++) {
-                list.appendChild(rowPairs[i].data);
-                list.appendChild(rowPairs[i].detail);
-            }
         }
         
-        // 初始化进度条
-        document.addEventListener('DOMContentLoaded', function() {
-            const progressBars = document.querySelectorAll('.progress-bar');
-            if (progressBars.length === 0) return;
-            
-            let maxTime = 0;
-            
-            // 找到最大耗时
-            progressBars.forEach(bar => {
-                // 在table结构中，time-text位于同一行的第三个td中
-                const row = bar.closest('tr');
-                if (row) {
-                    const timeCell = row.querySelector('.time-col');
-                    if (timeCell) {
-                        const timeText = timeCell.querySelector('.time-text');
-                        if (timeText) {
-                            const time = parseFloat(timeText.textContent);
-                            if (time > maxTime) {
-                                maxTime = time;
-                            }
-                        }
-                    }
-                }
+        // 生成请求的代码片段并复制到剪贴板，同时显示在文本框中
+        function copySnippet(button, format) {
+            const view = button.closest('.snippet-view');
+            const textarea = view.querySelector('.snippet-text');
+            const status = view.querySelector('.snippet-status');
+            view.querySelectorAll('.snippet-btn').forEach(function(b) {
+                b.classList.toggle('active', b === button);
             });
-            
-            // 设置进度条宽度
-            progressBars.forEach(bar => {
-                const row = bar.closest('tr');
-                if (row && maxTime > 0) {
-                    const timeCell = row.querySelector('.time-col');
-                    if (timeCell) {
-                        const timeText = timeCell.querySelector('.time-text');
-                        if (timeText) {
-                            const time = parseFloat(timeText.textContent);
-                            const width = (time / maxTime) * 100;
-                            bar.style.width = width + '%';
-                        }
+            fetch(textarea.dataset.src + '&format=' + format)
+                .then(function(resp) { return resp.text(); })
+                .then(function(text) {
+                    textarea.value = text;
+                    textarea.style.display = 'block';
+                    if (navigator.clipboard) {
+                        return navigator.clipboard.writeText(text);
                     }
-                }
-            });
-        });
+                    textarea.select();
+                    document.execCommand('copy');
+                })
+                .then(function() {
+                    status.textContent = '已复制';
+                })
+                .catch(function() {
+                    status.textContent = '复制失败，请手动复制';
+                });
+        }
+        
+        // 重放请求，并将新的响应与录制的响应并排显示
+        function replayEntry(form) {
+            const output = form.querySelector('.replay-output');
+            output.textContent = '请求中...';
+            fetch(form.action, {method: 'POST', body: new URLSearchParams(new FormData(form))})
+                .then(function(resp) { return resp.text(); })
+                .then(function(html) {
+                    output.innerHTML = html;
+                })
+                .catch(function() {
+                    output.textContent = '重放失败';
+                });
+        }
+        
+        // 对比两个文件并显示结果
+        function compareFiles(form) {
+            const output = form.querySelector('.compare-output');
+            output.textContent = '对比中...';
+            fetch(form.action + '?' + new URLSearchParams(new FormData(form)))
+                .then(function(resp) { return resp.text(); })
+                .then(function(html) {
+                    output.innerHTML = html;
+                })
+                .catch(function() {
+                    output.textContent = '对比失败';
+                });
+        }
+        
+        // 预览脱敏将替换的内容
+        function previewSanitize(form) {
+            const output = form.querySelector('.sanitize-output');
+            output.textContent = '处理中...';
+            fetch(form.action.replace('/sanitize/download', '/sanitize/preview'), {method: 'POST', body: new URLSearchParams(new FormData(form))})
+                .then(function(resp) { return resp.text(); })
+                .then(function(html) {
+                    output.innerHTML = html;
+                })
+                .catch(function() {
+                    output.textContent = '预览失败';
+                });
+        }
+        
+        // 按请求方法过滤
+        function filterByMethod(method) {
+            const input = document.querySelector('.filter-input');
+            if (!input) return;
+            input.value = method === 'OTHER' ? '-method:GET,POST' : 'method:' + method;
+            input.form.submit();
+        }
         
         // 滚动到顶部功能
         function scrollToTop() {
@@ -904,130 +1535,277 @@ func setupRoutes() {
 	http.HandleFunc("/", indexHandler)
 	http.HandleFunc("/upload", uploadHandler)
 	http.HandleFunc("/download-csv", downloadCSVHandler)
+	http.HandleFunc("/export", exportHandler)
 	http.HandleFunc("/reload", reloadHandler)
+	http.HandleFunc("/close", closeFileHandler)
+	http.HandleFunc("/progress", progressHandler)
+	http.HandleFunc("/entry-detail", entryDetailHandler)
+	http.HandleFunc("/snippet", snippetHandler)
+	http.HandleFunc("/replay", replayHandler)
+	http.HandleFunc("/mock/start", mockStartHandler)
+	http.HandleFunc("/mock/stop", mockStopHandler)
+	http.HandleFunc("/compare", compareHandler)
+	http.HandleFunc("/stats", statsHandler)
+	http.HandleFunc("/cookies", cookiesHandler)
+	http.HandleFunc("/endpoints", endpointsHandler)
+	http.HandleFunc("/sanitize/preview", sanitizePreviewHandler)
+	http.HandleFunc("/sanitize/download", sanitizeDownloadHandler)
+	http.HandleFunc("/request-body", requestBodyHandler)
+	http.HandleFunc("/response-body", responseBodyHandler)
+
+	// JSON API
+	setupAPIRoutes()
 }
 
 // 重新加载处理函数
 func reloadHandler(w http.ResponseWriter, r *http.Request) {
-	// 清空全局HAR数据
-	currentHARData = nil
+	sid := sessionFromRequest(r)
+	if sid == "" {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	// 停止Mock服务并清空会话中的所有HAR数据
+	stopMocks(sid, "", "")
+	store.closeAll(sid)
 	// 重定向到首页
-	http.Redirect(w, r, "/", http.StatusFound)
+	http.Redirect(w, r, sessionURL(sid, ""), http.StatusFound)
+}
+
+// 上传解析进度处理函数
+func progressHandler(w http.ResponseWriter, r *http.Request) {
+	uploadProgressMu.Lock()
+	progress, ok := uploadProgresses[r.URL.Query().Get("id")]
+	uploadProgressMu.Unlock()
+	if !ok {
+		http.Error(w, "未找到上传进度", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{
+		"read":    progress.Read.Load(),
+		"total":   progress.Total.Load(),
+		"entries": progress.Entries.Load(),
+	})
 }
 
 // 下载CSV处理函数
 func downloadCSVHandler(w http.ResponseWriter, r *http.Request) {
-	if currentHARData == nil {
-		http.Redirect(w, r, "/", http.StatusFound)
+	_, file, ok := fileFromRequest(w, r)
+	if !ok {
 		return
 	}
 
-	// 提取唯一域名
-	domains := extractUniqueDomains(currentHARData)
+	// 按域名统计
+	domains := collectDomainStats(file.HAR)
 
-	// 生成CSV内容（GBK编码）
-	csvContent := generateCSV(domains)
+	// 生成CSV内容，默认GBK编码
+	csvContent, charset := generateCSV(domains, r.URL.Query().Get("encoding"))
 
 	// 设置响应头
-	w.Header().Set("Content-Type", "text/csv; charset=GBK")
+	w.Header().Set("Content-Type", "text/csv; charset="+charset)
 	w.Header().Set("Content-Disposition", "attachment; filename=domains.csv")
 
 	// 写入响应
 	w.Write(csvContent)
 }
 
-func indexHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl, err := template.New("har").Parse(htmlTemplate)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("解析模板失败: %v", err), http.StatusInternalServerError)
-		return
+// 统计请求方法数量
+func countMethods(harData *HAR) (getCount, postCount, otherCount int) {
+	for _, entry := range harData.Log.Entries {
+		switch entry.Request.Method {
+		case "GET":
+			getCount++
+		case "POST":
+			postCount++
+		default:
+			otherCount++
+		}
 	}
-
-	tmpl.Execute(w, map[string]interface{}{
-		"HARData":         nil,
-		"MethodCountText": template.HTML(""),
-	})
+	return getCount, postCount, otherCount
 }
 
-func uploadHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Redirect(w, r, "/", http.StatusFound)
+func indexHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
 		return
 	}
 
-	// 解析表单
-	err := r.ParseMultipartForm(10 << 20) // 10MB
-	if err != nil {
-		http.Error(w, fmt.Sprintf("解析表单失败: %v", err), http.StatusInternalServerError)
+	// 没有会话时创建新会话
+	sid := sessionFromRequest(r)
+	if sid == "" {
+		http.Redirect(w, r, sessionURL(store.newSession(), ""), http.StatusFound)
 		return
 	}
 
-	// 获取上传的文件
-	file, header, err := r.FormFile("harfile")
+	tmpl, err := template.New("har").Parse(htmlTemplate)
+	if err == nil {
+		_, err = tmpl.New("pager").Parse(pagerTemplate)
+	}
+	if err == nil {
+		_, err = tmpl.New("csv-encoding").Parse(csvEncodingTemplate)
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("获取文件失败: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("解析模板失败: %v", err), http.StatusInternalServerError)
 		return
 	}
-	defer file.Close()
 
-	// 读取文件内容
-	content, err := ioutil.ReadAll(file)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("读取文件失败: %v", err), http.StatusInternalServerError)
-		return
+	// 使用文件列表快照渲染，避免渲染期间被其他请求修改
+	files := store.files(sid)
+	data := map[string]interface{}{
+		"SessionID":       sid,
+		"FileID":          "",
+		"Files":           files,
+		"HARData":         nil,
+		"MethodCountText": template.HTML(""),
+		"CaptureAccept":   captureAccept,
+		"UploadError":     r.URL.Query().Get("error"),
 	}
 
-	// 解析HAR文件
-	var harData HAR
-	err = json.Unmarshal(content, &harData)
-	if err != nil {
-		// 解析失败，重载页面到初始状态并显示错误
-		http.Redirect(w, r, "/?error=1", http.StatusFound)
+	// 未指定文件时显示最后加载的文件
+	var file *harFile
+	for _, f := range files {
+		if f.ID == r.URL.Query().Get("fid") {
+			file = f
+		}
+	}
+	if file == nil && len(files) > 0 {
+		file = files[len(files)-1]
+	}
+	// 登记为读取方，渲染期间文件被关闭时仍能读取临时文件
+	if file != nil {
+		file = store.acquireFile(r, sid, file.ID)
+	}
+	if file == nil {
+		tmpl.Execute(w, data)
 		return
 	}
 
 	// 统计请求方法数量
-	getCount := 0
-	postCount := 0
-	otherCount := 0
-	for _, entry := range harData.Log.Entries {
-		switch entry.Request.Method {
-		case "GET":
-			getCount++
-		case "POST":
-			postCount++
-		default:
-			otherCount++
-		}
-	}
+	getCount, postCount, otherCount := countMethods(file.HAR)
 
 	// 生成请求数量显示文本
 	var methodCounts []string
 	if getCount > 0 {
-		methodCounts = append(methodCounts, fmt.Sprintf("<span class=\"method-count get\" onclick=\"sortByMethod('GET')\">GET %d个</span>", getCount))
+		methodCounts = append(methodCounts, fmt.Sprintf("<span class=\"method-count get\" onclick=\"filterByMethod('GET')\">GET %d个</span>", getCount))
 	}
 	if postCount > 0 {
-		methodCounts = append(methodCounts, fmt.Sprintf("<span class=\"method-count post\" onclick=\"sortByMethod('POST')\">POST %d个</span>", postCount))
+		methodCounts = append(methodCounts, fmt.Sprintf("<span class=\"method-count post\" onclick=\"filterByMethod('POST')\">POST %d个</span>", postCount))
 	}
 	if otherCount > 0 {
-		methodCounts = append(methodCounts, fmt.Sprintf("<span class=\"method-count other\" onclick=\"sortByMethod('OTHER')\">其他 %d个</span>", otherCount))
+		methodCounts = append(methodCounts, fmt.Sprintf("<span class=\"method-count other\" onclick=\"filterByMethod('OTHER')\">其他 %d个</span>", otherCount))
 	}
 	methodCountText := strings.Join(methodCounts, "    ")
 
-	// 存储到全局变量
-	currentHARData = &harData
+	// 在服务端过滤、排序并分页，页面只渲染当前页的条目
+	options := parseListOptions(r, sid, file.ID)
+	filter, err := parseFilter(options.Query)
+	if err != nil {
+		data["FilterError"] = err.Error()
+	}
+	// 读取请求体或响应体失败时显示错误，列表中只有出错之前匹配的请求
+	indexes, err := filter.apply(file)
+	if err != nil {
+		data["FilterError"] = err.Error()
+	}
+	sortIndexes(file.HAR.Log.Entries, indexes, options.Sort, options.Order)
+	var groups map[string]*pageGroup
+	if options.Group && len(file.HAR.Log.Pages) > 0 {
+		groups = groupByPage(file.HAR, indexes)
+	}
+	pageIndexes, pagination := paginate(indexes, options)
+	sortLinks, sortMarks := options.sortLinks("method", "url", "status", "start", "time")
 
-	// 渲染模板
-	tmpl, err := template.New("har").Parse(htmlTemplate)
+	data["FileID"] = file.ID
+	data["HARData"] = file.HAR
+	data["Query"] = options.Query
+	data["Options"] = options
+	data["ExportColumns"] = exportColumns
+	data["DataExportFormats"] = dataExportFormats
+	data["Mocks"] = fileMocks(sid, file.ID)
+	data["RedactRules"] = builtinRedactRules
+	data["Rows"] = listRows(file.HAR.Log.Entries, pageIndexes, groups)
+	if len(file.HAR.Log.Pages) > 0 {
+		data["PageOptions"] = options.pageOptions(file.HAR.Log.Pages)
+		if options.Group {
+			data["GroupURL"] = options.url(map[string]string{"group": "", "page": ""})
+		} else {
+			data["GroupURL"] = options.url(map[string]string{"group": "page", "page": ""})
+		}
+	}
+	data["Pagination"] = pagination
+	data["SortLinks"] = sortLinks
+	data["SortMarks"] = sortMarks
+	data["FilterHelp"] = filterHelp
+	data["Waterfall"] = buildWaterfall(file.waterfallScale(), file.HAR.Log.Entries, pageIndexes)
+	data["FileName"] = file.Name
+	data["FileSize"] = formatFileSize(int(file.Size))
+	data["MethodCountText"] = template.HTML(methodCountText)
+	tmpl.Execute(w, data)
+}
+
+// 上传文件的大小限制
+const maxUploadSize int64 = 2 << 30
+
+func uploadHandler(w http.ResponseWriter, r *http.Request) {
+	sid := sessionFromRequest(r)
+	if sid == "" {
+		sid = store.newSession()
+	}
+	if r.Method != "POST" {
+		http.Redirect(w, r, sessionURL(sid, ""), http.StatusFound)
+		return
+	}
+
+	// 注册解析进度，供页面轮询
+	progress := &loadProgress{}
+	progress.Total.Store(r.ContentLength)
+	if id := r.URL.Query().Get("progress"); id != "" {
+		uploadProgressMu.Lock()
+		uploadProgresses[id] = progress
+		uploadProgressMu.Unlock()
+		defer func() {
+			uploadProgressMu.Lock()
+			delete(uploadProgresses, id)
+			uploadProgressMu.Unlock()
+		}()
+	}
+
+	// 流式读取表单，找到上传的文件
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	reader, err := r.MultipartReader()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("解析模板失败: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("解析表单失败: %v", err), http.StatusInternalServerError)
 		return
 	}
+	var part *multipart.Part
+	for {
+		part, err = reader.NextPart()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("获取文件失败: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if part.FormName() == "harfile" {
+			break
+		}
+		part.Close()
+	}
+	defer part.Close()
 
-	tmpl.Execute(w, map[string]interface{}{
-		"HARData":         harData,
-		"FileName":        header.Filename,
-		"FileSize":        formatFileSize(len(content)),
-		"MethodCountText": template.HTML(methodCountText),
-	})
+	// 流式解析HAR文件，其他抓包格式先转换为HAR
+	file, err := loadCapture(part.FileName(), part, progress)
+	if err != nil {
+		// 解析失败，重载页面到初始状态并显示错误信息
+		message := fmt.Sprintf("解析 %s 失败: %v", part.FileName(), err)
+		fmt.Printf("%s\n", message)
+		http.Redirect(w, r, sessionURL(sid, "")+"&error="+url.QueryEscape(message), http.StatusFound)
+		return
+	}
+
+	// 加入会话并跳转到新文件
+	if !store.addFile(sid, file) {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, sessionURL(sid, file.ID), http.StatusFound)
 }
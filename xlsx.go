@@ -0,0 +1,104 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xlsx 单元格内容长度上限
+const xlsxMaxCellLength = 32767
+
+// xlsx 文件中除工作表外的固定部分
+var xlsxStaticParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`},
+}
+
+// 表格单元格，Number 为 true 时按数值写入
+type xlsxCell struct {
+	Value  string
+	Number bool
+}
+
+// 列序号转为列名，0 -> A，26 -> AA
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// 写入只有一个工作表的 xlsx 文件
+func writeXLSX(w io.Writer, sheetName string, rows [][]xlsxCell) error {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxStaticParts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := zw.Create("xl/workbook.xml")
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(f, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="`)
+	xml.EscapeText(f, []byte(sheetName))
+	fmt.Fprint(f, `" sheetId="1" r:id="rId1"/></sheets></workbook>`)
+
+	f, err = zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(f, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range rows {
+		fmt.Fprintf(f, `<row r="%d">`, i+1)
+		for j, cell := range row {
+			ref := xlsxColumnName(j) + strconv.Itoa(i+1)
+			if cell.Number {
+				if _, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+					fmt.Fprintf(f, `<c r="%s"><v>%s</v></c>`, ref, cell.Value)
+					continue
+				}
+			}
+			value := cell.Value
+			if len(value) > xlsxMaxCellLength {
+				value = strings.ToValidUTF8(value[:xlsxMaxCellLength], "")
+			}
+			fmt.Fprintf(f, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			xml.EscapeText(f, []byte(value))
+			fmt.Fprint(f, `</t></is></c>`)
+		}
+		fmt.Fprint(f, `</row>`)
+	}
+	fmt.Fprint(f, `</sheetData></worksheet>`)
+
+	return zw.Close()
+}